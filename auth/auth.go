@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.sammcclenaghan.com/mango/http"
+)
+
+// DefaultTokenUrl is the MangaDex OpenID Connect token endpoint
+const DefaultTokenUrl = "https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/token"
+
+// expiryMargin renews access tokens a bit before they expire, so they don't expire while a request is in flight
+const expiryMargin = time.Minute
+
+// ErrNotLoggedIn is returned when there are no stored credentials
+var ErrNotLoggedIn = errors.New("not logged in, run mango login first")
+
+// Credentials holds a personal API client and the tokens of the user it logged in. The password is never stored.
+type Credentials struct {
+	// TokenUrl is the endpoint the tokens were issued by, they are refreshed against it
+	TokenUrl     string    `json:"token_url"`
+	ClientId     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret"`
+	Username     string    `json:"username"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// tokenResponse represents the response of the token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Login exchanges the username and password of the owner of a personal API client for session tokens. The token
+// endpoint defaults to DefaultTokenUrl when empty.
+func Login(tokenUrl, clientId, clientSecret, username, password string) (*Credentials, error) {
+	if tokenUrl == "" {
+		tokenUrl = DefaultTokenUrl
+	}
+	c := &Credentials{
+		TokenUrl:     tokenUrl,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Username:     username,
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	form.Set("username", username)
+	form.Set("password", password)
+	if err := c.requestToken(form); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	return c, nil
+}
+
+// Refresh renews the access token using the refresh token
+func (c *Credentials) Refresh() error {
+	if c.RefreshToken == "" {
+		return ErrNotLoggedIn
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", c.RefreshToken)
+	if err := c.requestToken(form); err != nil {
+		return fmt.Errorf("session expired, run mango login again: %w", err)
+	}
+
+	return nil
+}
+
+// Expired reports whether the access token has to be renewed before use
+func (c *Credentials) Expired() bool {
+	return c.AccessToken == "" || time.Now().Add(expiryMargin).After(c.Expiry)
+}
+
+// requestToken posts the grant in form to the token endpoint and stores the issued tokens
+func (c *Credentials) requestToken(form url.Values) error {
+	form.Set("client_id", c.ClientId)
+	form.Set("client_secret", c.ClientSecret)
+
+	rbody, err := http.Post(http.RequestParams{URL: c.TokenUrl}, "application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	defer rbody.Close()
+
+	body := tokenResponse{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		return err
+	}
+	if body.AccessToken == "" {
+		return errors.New("no access token in the response")
+	}
+
+	c.AccessToken = body.AccessToken
+	c.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	// refresh responses may not rotate the refresh token
+	if body.RefreshToken != "" {
+		c.RefreshToken = body.RefreshToken
+	}
+
+	return nil
+}
+
+// DefaultPath returns the credentials file in the user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "mango", "mangadex.json"), nil
+}
+
+// Load reads the credentials stored at path, returning ErrNotLoggedIn when there are none
+func Load(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Credentials{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Save stores the credentials at path, readable by the current user only
+func (c *Credentials) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a failed write never leaves a truncated credentials file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(0600); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Remove deletes the credentials stored at path
+func Remove(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotLoggedIn
+	}
+	return err
+}
+
+// Session hands out access tokens for stored credentials, refreshing and saving them when they expire
+type Session struct {
+	path  string
+	mu    sync.Mutex
+	creds *Credentials
+}
+
+// Open returns the session of the credentials stored at path
+func Open(path string) (*Session, error) {
+	creds, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSession(path, creds), nil
+}
+
+// NewSession returns the session of freshly issued credentials, refreshed tokens are saved at path unless it's empty
+func NewSession(path string, creds *Credentials) *Session {
+	return &Session{path: path, creds: creds}
+}
+
+// Username returns the name of the logged in user
+func (s *Session) Username() string {
+	return s.creds.Username
+}
+
+// Token returns a valid access token
+func (s *Session) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.creds.Expired() {
+		return s.creds.AccessToken, nil
+	}
+
+	if err := s.creds.Refresh(); err != nil {
+		return "", err
+	}
+	if s.path == "" {
+		return s.creds.AccessToken, nil
+	}
+	if err := s.creds.Save(s.path); err != nil {
+		return "", fmt.Errorf("error saving refreshed credentials: %w", err)
+	}
+
+	return s.creds.AccessToken, nil
+}
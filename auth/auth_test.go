@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tokenServer stubs the token endpoint, issuing tokens numbered by request
+func tokenServer(t *testing.T) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("client_id") != "client" || r.Form.Get("client_secret") != "secret" {
+			t.Errorf("token request without client credentials: %v", r.Form)
+		}
+
+		switch r.Form.Get("grant_type") {
+		case "password":
+			if r.Form.Get("username") != "reader" || r.Form.Get("password") != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		default:
+			t.Errorf("unexpected grant type %q", r.Form.Get("grant_type"))
+		}
+
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{"access_token": "access-1", "refresh_token": "refresh-1", "expires_in": 900}`))
+		} else {
+			w.Write([]byte(`{"access_token": "access-2", "expires_in": 900}`))
+		}
+	}))
+}
+
+func TestLogin(t *testing.T) {
+	ts := tokenServer(t)
+	defer ts.Close()
+
+	c, err := Login(ts.URL, "client", "secret", "reader", "hunter2")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if c.AccessToken != "access-1" || c.RefreshToken != "refresh-1" || c.TokenUrl != ts.URL {
+		t.Errorf("Login() = %+v, want the issued tokens", c)
+	}
+
+	if c.Expired() {
+		t.Error("Expired() = true for a fresh token")
+	}
+
+	if _, err = Login(ts.URL, "client", "secret", "reader", "wrong"); err == nil {
+		t.Error("Login() error = nil for a wrong password")
+	}
+}
+
+func TestCredentials_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mango", "mangadex.json")
+
+	if _, err := Load(path); !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("Load() error = %v, want ErrNotLoggedIn", err)
+	}
+
+	c := &Credentials{TokenUrl: "https://auth.example.com", ClientId: "client", RefreshToken: "refresh-1"}
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file permissions = %o, want 600", perm)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if *loaded != *c {
+		t.Errorf("Load() = %+v, want %+v", loaded, c)
+	}
+
+	if err = Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err = Remove(path); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("Remove() error = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestSession_Token(t *testing.T) {
+	ts := tokenServer(t)
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "mangadex.json")
+	c, err := Login(ts.URL, "client", "secret", "reader", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if token, err := s.Token(); err != nil || token != "access-1" {
+		t.Errorf("Token() = %q, %v, want the stored token", token, err)
+	}
+
+	// an expired token is refreshed and the new one saved, keeping the refresh token
+	s.creds.Expiry = time.Now()
+	if token, err := s.Token(); err != nil || token != "access-2" {
+		t.Fatalf("Token() = %q, %v, want a refreshed token", token, err)
+	}
+
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != "access-2" || saved.RefreshToken != "refresh-1" {
+		t.Errorf("saved credentials = %+v, want the refreshed token", saved)
+	}
+}
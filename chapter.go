@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+// fetchChapterURL fetches the single chapter a chapter URL points to, under the title it belongs to. The chapter
+// range, labels and volumes are ignored since the chapter was picked already.
+func fetchChapterURL(resolver grabber.ChapterResolver, opts Options) (string, error) {
+	titleURL, chapter, err := resolver.ResolveChapter()
+	if err != nil {
+		return "", fmt.Errorf("error resolving chapter: %w", err)
+	}
+
+	site, err := grabber.New(&grabber.Grabber{URL: titleURL, Settings: opts.Settings})
+	if err != nil {
+		return "", err
+	}
+
+	title, err := fetchTitle(site)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.Info {
+		return seriesInfo(site, title)
+	}
+
+	return fetchResolvedChapter(site, chapter, title, opts)
+}
+
+// fetchResolvedChapter lists or downloads a chapter picked by URL
+func fetchResolvedChapter(site grabber.GrabberInterface, chapter grabber.Filterable, title string, opts Options) (string, error) {
+	opts.ChapterRange = ""
+	opts.ChapterLabels = nil
+	opts.VolumeRange = ""
+	// the chapter was asked for explicitly, so its group is never blocked
+	opts.Settings.BlockedGroups = nil
+	if opts.ListOnly {
+		opts.Download = false
+	}
+
+	return fetchChapterRange(site, grabber.Filterables{chapter}, title, opts)
+}
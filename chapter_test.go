@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+func TestFetchResolvedChapter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{Number: 12.5, Label: "12.5", Title: "Side Story", Language: "en", Groups: []string{"Blocked Scans"}}
+
+	// the range and blocked groups of the command line don't apply to a chapter picked by URL
+	outputDir := t.TempDir()
+	opts := Options{
+		ChapterRange: "1-3",
+		Download:     true,
+		SaveCBZ:      true,
+		OutputDir:    outputDir,
+		Settings:     grabber.Settings{BlockedGroups: []string{"Blocked Scans"}},
+	}
+
+	content, err := fetchResolvedChapter(&fakeSite{pagesURL: ts.URL}, chapter, "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchResolvedChapter() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Found 1 unique chapters") {
+		t.Errorf("unexpected output:\n%s", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "Fake Manga - Chapter 12.5 - Side Story.cbz")); err != nil {
+		t.Errorf("expected the CBZ to be named after the title and chapter: %v", err)
+	}
+}
+
+func TestFetchURLContent_GroupURL(t *testing.T) {
+	_, err := FetchURLContent("https://mangadex.org/group/9a414441-bbad-43f1-a3a7-dc262ca790a3/group", Options{})
+	if err == nil || !strings.Contains(err.Error(), "scanlation group") {
+		t.Errorf("FetchURLContent() error = %v, want group URLs to be rejected", err)
+	}
+}
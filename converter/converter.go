@@ -29,13 +29,31 @@ type Converter struct {
 	DeleteSource bool
 	// OutputDir is the directory where converted files will be saved
 	OutputDir string
+	// Cover is the path of an image used as the cover of converted ebooks, the first page is used when empty
+	Cover string
+	// Metadata is written to converted ebooks when set
+	Metadata *Metadata
+	// LongStrip converts vertical comics, keeping the proportions and colors of their tall pages
+	LongStrip bool
+}
+
+// Metadata describes the book written by a conversion
+type Metadata struct {
+	Title       string
+	Series      string
+	SeriesIndex string
+	Authors     []string
+	Tags        []string
+	Comments    string
+	Language    string
+	Publisher   string
 }
 
 // NewConverter creates a new converter with default settings
 func NewConverter() *Converter {
 	return &Converter{
 		MaxConcurrency: 1, // Conservative default to avoid overwhelming the system
-		DeleteSource:   true,
+		DeleteSource:   false,
 		OutputDir:      ".",
 	}
 }
@@ -67,7 +85,7 @@ func (c *Converter) ConvertCBZToAZW3(inputFile string, outputFile string) (*Conv
 	}
 
 	// Run ebook-convert command
-	cmd := exec.Command("/Applications/calibre.app/Contents/MacOS/ebook-convert", inputFile, outputFile)
+	cmd := exec.Command("/Applications/calibre.app/Contents/MacOS/ebook-convert", c.args(inputFile, outputFile)...)
 
 	// Capture output for debugging
 	output, err := cmd.CombinedOutput()
@@ -85,11 +103,7 @@ func (c *Converter) ConvertCBZToAZW3(inputFile string, outputFile string) (*Conv
 	}
 
 	result.Success = true
-
-	if err := os.Remove(inputFile); err != nil {
-		// Don't fail the conversion if we can't delete the source
-		result.Error = fmt.Errorf("conversion successful but failed to delete source file: %w", err)
-	}
+	c.deleteSource(result)
 
 	return result, nil
 }
@@ -121,7 +135,7 @@ func (c *Converter) ConvertCBZToEPUB(inputFile string, outputFile string) (*Conv
 	}
 
 	// Run ebook-convert command
-	cmd := exec.Command("/Applications/calibre.app/Contents/MacOS/ebook-convert", inputFile, outputFile)
+	cmd := exec.Command("/Applications/calibre.app/Contents/MacOS/ebook-convert", c.args(inputFile, outputFile)...)
 
 	// Capture output for debugging
 	output, err := cmd.CombinedOutput()
@@ -139,11 +153,7 @@ func (c *Converter) ConvertCBZToEPUB(inputFile string, outputFile string) (*Conv
 	}
 
 	result.Success = true
-
-	if err := os.Remove(inputFile); err != nil {
-		// Don't fail the conversion if we can't delete the source
-		result.Error = fmt.Errorf("conversion successful but failed to delete source file: %w", err)
-	}
+	c.deleteSource(result)
 
 	return result, nil
 }
@@ -294,7 +304,7 @@ func (c *Converter) ConvertCBZToFormat(inputFile, outputFile, format string) (*C
 	}
 
 	// Run ebook-convert command
-	cmd := exec.Command("ebook-convert", inputFile, outputFile)
+	cmd := exec.Command("ebook-convert", c.args(inputFile, outputFile)...)
 
 	// Capture output for debugging
 	output, err := cmd.CombinedOutput()
@@ -312,13 +322,53 @@ func (c *Converter) ConvertCBZToFormat(inputFile, outputFile, format string) (*C
 	}
 
 	result.Success = true
+	c.deleteSource(result)
+
+	return result, nil
+}
 
-	if err := os.Remove(inputFile); err != nil {
+// args returns the ebook-convert arguments converting inputFile to outputFile
+func (c *Converter) args(inputFile, outputFile string) []string {
+	args := []string{inputFile, outputFile}
+	if c.Cover != "" {
+		args = append(args, "--cover", c.Cover)
+	}
+	if m := c.Metadata; m != nil {
+		args = appendOption(args, "--title", m.Title)
+		args = appendOption(args, "--series", m.Series)
+		args = appendOption(args, "--series-index", m.SeriesIndex)
+		// ebook-convert separates authors with an ampersand and tags with commas
+		args = appendOption(args, "--authors", strings.Join(m.Authors, " & "))
+		args = appendOption(args, "--tags", strings.Join(m.Tags, ","))
+		args = appendOption(args, "--comments", m.Comments)
+		args = appendOption(args, "--language", m.Language)
+		args = appendOption(args, "--publisher", m.Publisher)
+	}
+	if c.LongStrip {
+		// long strips are in color and squashing their tall pages to the screen size makes them unreadable
+		args = append(args, "--keep-aspect-ratio", "--dont-grayscale")
+	}
+	return args
+}
+
+// appendOption appends an ebook-convert option unless its value is empty
+func appendOption(args []string, option, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, option, value)
+}
+
+// deleteSource removes the input file of a successful conversion when DeleteSource is set
+func (c *Converter) deleteSource(result *ConversionResult) {
+	if !c.DeleteSource {
+		return
+	}
+
+	if err := os.Remove(result.InputFile); err != nil {
 		// Don't fail the conversion if we can't delete the source
 		result.Error = fmt.Errorf("conversion successful but failed to delete source file: %w", err)
 	}
-
-	return result, nil
 }
 
 // checkEbookConvert verifies that ebook-convert is available
@@ -369,6 +369,25 @@ func TestConverterDeleteSource(t *testing.T) {
 	}
 }
 
+func TestConverterDeleteSource_KeepsCBZ(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "chapter.cbz")
+	if err := os.WriteFile(input, []byte("cbz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter()
+	converter.deleteSource(&ConversionResult{InputFile: input})
+	if _, err := os.Stat(input); err != nil {
+		t.Errorf("Expected the CBZ to be kept by default: %v", err)
+	}
+
+	converter.DeleteSource = true
+	converter.deleteSource(&ConversionResult{InputFile: input})
+	if _, err := os.Stat(input); !os.IsNotExist(err) {
+		t.Errorf("Expected the CBZ to be deleted when DeleteSource is set, got %v", err)
+	}
+}
+
 func TestConverterOutputDir(t *testing.T) {
 	converter := NewConverter()
 	converter.OutputDir = "/custom/output"
@@ -407,3 +426,42 @@ func TestConversionResultStruct(t *testing.T) {
 		t.Errorf("Expected BytesWritten to be 1024, got %d", result.BytesWritten)
 	}
 }
+
+func TestConverterArgs(t *testing.T) {
+	converter := NewConverter()
+
+	if got := strings.Join(converter.args("in.cbz", "out.epub"), " "); got != "in.cbz out.epub" {
+		t.Errorf("args() = %q, want %q", got, "in.cbz out.epub")
+	}
+
+	converter.Cover = "cover.jpg"
+	if got := strings.Join(converter.args("in.cbz", "out.epub"), " "); got != "in.cbz out.epub --cover cover.jpg" {
+		t.Errorf("args() = %q, want the cover option", got)
+	}
+}
+
+func TestConverterArgs_Metadata(t *testing.T) {
+	converter := NewConverter()
+	converter.Metadata = &Metadata{
+		Title:       "Manga - Vol 01",
+		Series:      "Manga",
+		SeriesIndex: "1",
+		Authors:     []string{"Writer", "Artist"},
+		Tags:        []string{"Action", "Pirates"},
+	}
+
+	want := "in.cbz out.epub --title Manga - Vol 01 --series Manga --series-index 1 --authors Writer & Artist --tags Action,Pirates"
+	if got := strings.Join(converter.args("in.cbz", "out.epub"), " "); got != want {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
+
+func TestConverterArgs_LongStrip(t *testing.T) {
+	converter := NewConverter()
+	converter.LongStrip = true
+
+	want := "in.cbz out.epub --keep-aspect-ratio --dont-grayscale"
+	if got := strings.Join(converter.args("in.cbz", "out.epub"), " "); got != want {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.sammcclenaghan.com/mango/colors"
+	"github.sammcclenaghan.com/mango/downloader"
+	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/http"
+)
+
+// seriesCoverFilename is the name of the series cover saved next to the downloaded files
+const seriesCoverFilename = "cover.jpg"
+
+// coverArt holds the covers of a series, downloading each image only once
+type coverArt struct {
+	covers []grabber.Cover
+	images map[string][]byte
+}
+
+// loadCovers fetches the covers of the series, returning nil when the site has none
+func loadCovers(site grabber.GrabberInterface) *coverArt {
+	fetcher, ok := site.(grabber.CoverFetcher)
+	if !ok {
+		return nil
+	}
+
+	covers, err := fetcher.FetchCovers()
+	if err != nil {
+		colors.WarningPrintf("Warning: could not fetch cover art: %v\n", err)
+		return nil
+	}
+
+	return &coverArt{covers: covers, images: make(map[string][]byte)}
+}
+
+// image returns the cover of the volume, falling back to the series cover, or nil when there is none
+func (c *coverArt) image(volume string) []byte {
+	if c == nil {
+		return nil
+	}
+
+	cover, ok := grabber.CoverFor(c.covers, volume)
+	if !ok {
+		return nil
+	}
+
+	if data, cached := c.images[cover.URL]; cached {
+		return data
+	}
+
+	file, err := downloader.FetchFile(http.RequestParams{URL: cover.URL}, 0)
+	if err != nil {
+		colors.WarningPrintf("Warning: could not download cover %s: %v\n", cover.URL, err)
+		c.images[cover.URL] = nil
+		return nil
+	}
+
+	c.images[cover.URL] = file.Data
+	return file.Data
+}
+
+// saveSeriesCover writes the series cover as cover.jpg in dir, returning the path of the file
+func (c *coverArt) saveSeriesCover(dir string) (string, error) {
+	data := c.image("")
+	if data == nil {
+		return "", nil
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	filename := filepath.Join(dir, seriesCoverFilename)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save cover: %w", err)
+	}
+
+	return filename, nil
+}
+
+// writeTempCover writes a cover to a temporary file so it can be passed to ebook-convert
+func writeTempCover(data []byte) (string, error) {
+	f, err := os.CreateTemp("", "mango-cover-*.jpg")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), f.Close()
+}
@@ -1,11 +1,16 @@
 package downloader
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.sammcclenaghan.com/mango/colors"
 	"github.sammcclenaghan.com/mango/grabber"
 	"github.sammcclenaghan.com/mango/http"
 )
@@ -19,95 +24,269 @@ type File struct {
 // ProgressCallback is a function type for progress updates with optional error
 type ProgressCallback func(page, progress int, err error)
 
-// FetchChapter downloads all the pages of a chapter
+// maxRefreshAttempts bounds the image sources tried for a chapter, in case a site never runs out of them
+const maxRefreshAttempts = 10
+
+// FetchChapter downloads all the pages of a chapter. When some pages fail and the site can provide other image
+// sources, the chapter is refreshed and only the missing pages are downloaded again.
 func FetchChapter(site grabber.GrabberInterface, chapter *grabber.Chapter, onprogress ProgressCallback) (files []*File, err error) {
 	if len(chapter.Pages) == 0 {
 		return []*File{}, nil
 	}
 
+	reports := newReporter(site)
+	defer reports.close()
+
+	files, failed := fetchPages(site, chapter.Pages, reports, onprogress)
+
+	if refresher, ok := site.(grabber.Refresher); ok {
+		for attempt := 0; len(failed) > 0 && attempt < maxRefreshAttempts; attempt++ {
+			refreshed, err := refresher.RefreshChapter(chapter, attempt)
+			if errors.Is(err, grabber.ErrNoImageSources) {
+				colors.DebugPrintf("Debug: no more image sources for chapter %.1f: %v\n", chapter.Number, err)
+				break
+			}
+			if err != nil {
+				// a failing source doesn't mean the next one fails too
+				colors.DebugPrintf("Debug: image source %d of chapter %.1f failed: %v\n", attempt+1, chapter.Number, err)
+				continue
+			}
+
+			pages := missingPages(refreshed.Pages, failed)
+			if len(pages) == 0 {
+				break
+			}
+
+			colors.DebugPrintf("Debug: retrying %d pages of chapter %.1f (attempt %d)\n", len(pages), chapter.Number, attempt+1)
+			retried, stillFailed := fetchPages(site, pages, reports, onprogress)
+			files = append(files, retried...)
+			failed = stillFailed
+		}
+	}
+
+	if len(failed) > 0 {
+		// report the first failure, like a download without retries would
+		idx, first := 0, failed[0]
+		for i, page := range chapter.Pages {
+			if page.Number == first.page.Number {
+				idx = i
+			}
+		}
+		err = fmt.Errorf("page %d: %w", first.page.Number, first.err)
+		onprogress(idx, idx, err)
+		return nil, err
+	}
+
+	// sort files by page number
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Page < files[j].Page
+	})
+
+	return
+}
+
+// pageError is a page that could not be downloaded
+type pageError struct {
+	page grabber.Page
+	err  error
+}
+
+// fetchPages downloads the given pages concurrently, returning the downloaded files and the pages that failed
+func fetchPages(site grabber.GrabberInterface, pages []grabber.Page, reports *reporter, onprogress ProgressCallback) ([]*File, []pageError) {
 	wg := sync.WaitGroup{}
 	guard := make(chan struct{}, 5) // Default max concurrency of 5
-	errChan := make(chan error, 1)
-	done := make(chan bool)
-	fileChan := make(chan *File, len(chapter.Pages))
-	var downloadErr error
+	mu := sync.Mutex{}
+
+	files := make([]*File, 0, len(pages))
+	var failed []pageError
 
-	for i, page := range chapter.Pages {
+	for i, page := range pages {
 		guard <- struct{}{}
 		wg.Add(1)
 		go func(page grabber.Page, idx int) {
 			defer wg.Done()
 
-			file, err := FetchFile(http.RequestParams{
-				URL: page.URL,
-			}, uint(page.Number))
+			start := time.Now()
+			file, cached, err := fetchPage(site, page)
+			duration := time.Since(start)
+			<-guard
+
+			// the report is queued once the download slot is free, it's sent in the background
+			reports.send(imageReport(page, file, cached, duration, err))
+			if err == nil && page.EncryptionKey != "" {
+				file.Data, err = decrypt(file.Data, page.EncryptionKey)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
 
 			if err != nil {
-				select {
-				case errChan <- fmt.Errorf("page %d: %w", page.Number, err):
-					onprogress(idx, idx, err)
-				default:
-				}
-				<-guard
+				failed = append(failed, pageError{page: page, err: err})
 				return
 			}
 
-			fileChan <- file
+			files = append(files, file)
 			onprogress(1, idx, nil) // Progress by 1 page at a time
-			<-guard
 		}(page, i)
 	}
 
-	go func() {
-		wg.Wait()
-		close(done)
-		close(fileChan)
-	}()
-
-	// Collect files from channel
-	files = make([]*File, 0, len(chapter.Pages))
-
-	collecting := true
-	for collecting {
-		select {
-		case err := <-errChan:
-			downloadErr = err
-			collecting = false
-		case file := <-fileChan:
-			if file != nil {
-				files = append(files, file)
-			}
-		case <-done:
-			collecting = false
+	wg.Wait()
+
+	// keep failures in page order so the reported error doesn't depend on scheduling
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].page.Number < failed[j].page.Number
+	})
+
+	return files, failed
+}
+
+// fetchPage gets the image of a page, from the site itself when it serves its pages without HTTP
+func fetchPage(site grabber.GrabberInterface, page grabber.Page) (*File, bool, error) {
+	if fetcher, ok := site.(grabber.PageFetcher); ok {
+		data, err := fetcher.FetchPage(page)
+		if err != nil {
+			return nil, false, err
 		}
+		return &File{Data: data, Page: uint(page.Number)}, false, nil
 	}
 
-	// Collect any remaining files from the channel
-	for file := range fileChan {
-		if file != nil {
-			files = append(files, file)
+	return fetchFile(http.RequestParams{
+		URL:     page.URL,
+		Referer: page.Referer,
+	}, uint(page.Number))
+}
+
+// missingPages returns the pages matching the numbers of the failed pages
+func missingPages(pages []grabber.Page, failed []pageError) []grabber.Page {
+	missing := make(map[int64]bool, len(failed))
+	for _, f := range failed {
+		missing[f.page.Number] = true
+	}
+
+	var result []grabber.Page
+	for _, page := range pages {
+		if missing[page.Number] {
+			result = append(result, page)
 		}
 	}
 
-	if downloadErr != nil {
-		return nil, downloadErr
+	return result
+}
+
+// reportQueueSize is the number of image reports waiting to be sent, reports past it are dropped
+const reportQueueSize = 64
+
+// reportDrainTimeout is how long a finished download waits for its queued reports to be sent
+var reportDrainTimeout = 5 * time.Second
+
+// reporter sends image reports to the site from a single background worker, so reporting never holds up downloads.
+// Reports are best effort: failures are only logged and never fail the download.
+type reporter struct {
+	site  grabber.Reporter
+	queue chan grabber.ImageReport
+	done  chan struct{}
+}
+
+// newReporter starts the report worker of the site, it returns nil when the site doesn't want reports
+func newReporter(site grabber.GrabberInterface) *reporter {
+	r, ok := site.(grabber.Reporter)
+	if !ok {
+		return nil
 	}
 
-	// sort files by page number
-	sort.SliceStable(files, func(i, j int) bool {
-		return files[i].Page < files[j].Page
-	})
+	rep := &reporter{
+		site:  r,
+		queue: make(chan grabber.ImageReport, reportQueueSize),
+		done:  make(chan struct{}),
+	}
+	go rep.run()
 
-	return
+	return rep
+}
+
+// run sends the queued reports until the queue is closed
+func (r *reporter) run() {
+	defer close(r.done)
+	for report := range r.queue {
+		if err := r.site.Report(report); err != nil {
+			colors.DebugPrintf("Debug: image report for %s failed: %v\n", report.URL, err)
+		}
+	}
+}
+
+// send queues a report without blocking, the report is dropped when the queue is full
+func (r *reporter) send(report grabber.ImageReport) {
+	if r == nil {
+		return
+	}
+
+	select {
+	case r.queue <- report:
+	default:
+		colors.DebugPrintf("Debug: dropping image report for %s, too many reports pending\n", report.URL)
+	}
+}
+
+// close stops taking reports and waits a little for the queued ones, the rest are sent in the background
+func (r *reporter) close() {
+	if r == nil {
+		return
+	}
+
+	close(r.queue)
+	select {
+	case <-r.done:
+	case <-time.After(reportDrainTimeout):
+		colors.DebugPrintf("Debug: image reports still pending, sending them in the background\n")
+	}
+}
+
+// imageReport describes how a page download went
+func imageReport(page grabber.Page, file *File, cached bool, duration time.Duration, err error) grabber.ImageReport {
+	r := grabber.ImageReport{
+		URL:      page.URL,
+		Success:  err == nil,
+		Duration: duration,
+		Cached:   cached,
+	}
+	if file != nil {
+		r.Bytes = len(file.Data)
+	}
+
+	return r
+}
+
+// decrypt decodes an image XOR encrypted with a repeating hex encoded key
+func decrypt(data []byte, key string) ([]byte, error) {
+	k, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if len(k) == 0 {
+		return data, nil
+	}
+
+	for i := range data {
+		data[i] ^= k[i%len(k)]
+	}
+
+	return data, nil
 }
 
 // FetchFile gets an online file returning a new *File with its contents
 func FetchFile(params http.RequestParams, page uint) (file *File, err error) {
-	body, err := http.Get(params)
+	file, _, err = fetchFile(params, page)
+	return
+}
+
+// fetchFile gets an online file, also reporting whether the server answered from its cache
+func fetchFile(params http.RequestParams, page uint) (file *File, cached bool, err error) {
+	body, headers, err := http.GetWithHeaders(params)
 	if err != nil {
 		// TODO: should retry at least once (configurable)
 		return
 	}
+	cached = strings.HasPrefix(headers.Get("X-Cache"), "HIT")
 
 	defer body.Close()
 
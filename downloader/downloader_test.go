@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -304,3 +305,381 @@ func TestFetchChapter_Concurrency(t *testing.T) {
 		t.Errorf("FetchChapter() took %v, expected less than %v (concurrency not working)", duration, maxExpectedDuration)
 	}
 }
+
+// reportingGrabber records the image reports it receives
+type reportingGrabber struct {
+	MockGrabber
+	mu      sync.Mutex
+	reports []grabber.ImageReport
+}
+
+func (r *reportingGrabber) Report(report grabber.ImageReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestFetchChapter_Reports(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page1.jpg":
+			w.Header().Set("X-Cache", "HIT from upstream")
+			w.Write([]byte("page 1 data"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages: []grabber.Page{
+			{Number: 1, URL: ts.URL + "/page1.jpg"},
+			{Number: 2, URL: ts.URL + "/page2.jpg"},
+		},
+	}
+
+	site := &reportingGrabber{}
+	if _, err := FetchChapter(site, chapter, func(int, int, error) {}); err == nil {
+		t.Fatal("FetchChapter() expected error for missing page, but got none")
+	}
+
+	if len(site.reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(site.reports))
+	}
+
+	for _, r := range site.reports {
+		switch r.URL {
+		case chapter.Pages[0].URL:
+			if !r.Success || !r.Cached || r.Bytes != len("page 1 data") {
+				t.Errorf("page 1 report = %+v, want a successful cached report of 11 bytes", r)
+			}
+		case chapter.Pages[1].URL:
+			if r.Success || r.Cached || r.Bytes != 0 {
+				t.Errorf("page 2 report = %+v, want a failed report", r)
+			}
+		default:
+			t.Errorf("unexpected report for %s", r.URL)
+		}
+	}
+}
+
+// slowReporter is a site whose report endpoint never answers in time
+type slowReporter struct {
+	MockGrabber
+	release chan struct{}
+}
+
+func (s *slowReporter) Report(grabber.ImageReport) error {
+	<-s.release
+	return fmt.Errorf("report endpoint timed out")
+}
+
+func TestFetchChapter_SlowReports(t *testing.T) {
+	orig := reportDrainTimeout
+	reportDrainTimeout = 50 * time.Millisecond
+	defer func() { reportDrainTimeout = orig }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{Number: 1}
+	for i := 1; i <= 20; i++ {
+		chapter.Pages = append(chapter.Pages, grabber.Page{Number: int64(i), URL: fmt.Sprintf("%s/%03d.jpg", ts.URL, i)})
+	}
+
+	site := &slowReporter{release: make(chan struct{})}
+	defer close(site.release)
+
+	done := make(chan struct{})
+	var files []*File
+	var err error
+	go func() {
+		files, err = FetchChapter(site, chapter, func(int, int, error) {})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("FetchChapter() is held up by the reports")
+	}
+	if err != nil || len(files) != 20 {
+		t.Errorf("FetchChapter() = %d files, %v, want every page", len(files), err)
+	}
+}
+
+// refreshingGrabber serves the pages of a chapter from a working host once refreshed
+type refreshingGrabber struct {
+	MockGrabber
+	host string
+	// sources is the number of image sources, two when unset
+	sources int
+	// failing holds the errors of the sources which can't be refreshed
+	failing  map[int]error
+	attempts []int
+}
+
+func (r *refreshingGrabber) RefreshChapter(chapter *grabber.Chapter, attempt int) (*grabber.Chapter, error) {
+	r.attempts = append(r.attempts, attempt)
+	sources := r.sources
+	if sources == 0 {
+		sources = 2
+	}
+	if attempt >= sources {
+		return nil, grabber.ErrNoImageSources
+	}
+	if err, ok := r.failing[attempt]; ok {
+		return nil, err
+	}
+
+	refreshed := *chapter
+	refreshed.Pages = nil
+	for _, page := range chapter.Pages {
+		page.URL = fmt.Sprintf("%s/node%d/page%d.jpg", r.host, attempt, page.Number)
+		refreshed.Pages = append(refreshed.Pages, page)
+	}
+
+	return &refreshed, nil
+}
+
+func TestFetchChapter_Failover(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/broken/page1.jpg", "/node1/page2.jpg":
+			w.Write([]byte("data"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages: []grabber.Page{
+			{Number: 1, URL: ts.URL + "/broken/page1.jpg"},
+			{Number: 2, URL: ts.URL + "/broken/page2.jpg"},
+		},
+	}
+
+	site := &refreshingGrabber{host: ts.URL}
+	files, err := FetchChapter(site, chapter, func(page, progress int, err error) {
+		if err != nil {
+			t.Errorf("Unexpected error in progress callback: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	if len(files) != 2 || files[0].Page != 1 || files[1].Page != 2 {
+		t.Fatalf("FetchChapter() files = %v, want pages 1 and 2", files)
+	}
+
+	if fmt.Sprint(site.attempts) != "[0 1]" {
+		t.Errorf("refresh attempts = %v, want [0 1]", site.attempts)
+	}
+
+	// only the missing page is downloaded again
+	if requests["/node0/page1.jpg"] != 0 || requests["/node1/page1.jpg"] != 0 {
+		t.Errorf("page 1 was downloaded again: %v", requests)
+	}
+}
+
+func TestFetchChapter_FailoverExhausted(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages:  []grabber.Page{{Number: 1, URL: ts.URL + "/broken/page1.jpg"}},
+	}
+
+	site := &refreshingGrabber{host: ts.URL}
+	files, err := FetchChapter(site, chapter, func(int, int, error) {})
+	if err == nil {
+		t.Fatal("FetchChapter() expected error once every source failed, but got none")
+	}
+	if files != nil {
+		t.Error("FetchChapter() expected nil files when error occurs")
+	}
+
+	if fmt.Sprint(site.attempts) != "[0 1 2]" {
+		t.Errorf("refresh attempts = %v, want [0 1 2]", site.attempts)
+	}
+}
+
+func TestFetchChapter_FailoverRefreshError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/node2/page1.jpg" {
+			w.Write([]byte("data"))
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages:  []grabber.Page{{Number: 1, URL: ts.URL + "/broken/page1.jpg"}},
+	}
+
+	// the first source can't be refreshed, the next ones are still tried
+	site := &refreshingGrabber{
+		host:    ts.URL,
+		sources: 3,
+		failing: map[int]error{0: &httpPkg.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}},
+	}
+	files, err := FetchChapter(site, chapter, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+	if len(files) != 1 || string(files[0].Data) != "data" {
+		t.Errorf("FetchChapter() files = %v, want the page from the third source", files)
+	}
+
+	if fmt.Sprint(site.attempts) != "[0 1 2]" {
+		t.Errorf("refresh attempts = %v, want [0 1 2]", site.attempts)
+	}
+}
+
+func TestFetchChapter_Referer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Referer() != "https://www.webtoons.com/" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("strip"))
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages: []grabber.Page{
+			{Number: 1, URL: ts.URL + "/001.jpg", Referer: "https://www.webtoons.com/"},
+		},
+	}
+
+	files, err := FetchChapter(nil, chapter, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v, want the page referer to be sent", err)
+	}
+	if len(files) != 1 || string(files[0].Data) != "strip" {
+		t.Errorf("FetchChapter() = %v, want the page", files)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		key      string
+		expected []byte
+		wantErr  bool
+	}{
+		{name: "key shorter than the data repeats", data: []byte{0x01, 0x02, 0x03, 0x04, 0x05}, key: "ff00", expected: []byte{0xfe, 0x02, 0xfc, 0x04, 0xfa}},
+		{name: "key longer than the data", data: []byte{0x0f}, key: "f0f0f0", expected: []byte{0xff}},
+		{name: "invalid key", data: []byte{0x01}, key: "zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := decrypt(tt.data, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(result, tt.expected) {
+				t.Errorf("decrypt() = %x, want %x", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFetchChapter_Encrypted(t *testing.T) {
+	key := []byte{0x5a, 0xc3, 0x11}
+	image := []byte("\x89PNG page image")
+	encrypted := make([]byte, len(image))
+	for i := range image {
+		encrypted[i] = image[i] ^ key[i%len(key)]
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encrypted)
+	}))
+	defer ts.Close()
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages: []grabber.Page{
+			{Number: 1, URL: ts.URL + "/encrypted.jpg", EncryptionKey: "5ac311"},
+			{Number: 2, URL: ts.URL + "/plain.jpg"},
+		},
+	}
+
+	files, err := FetchChapter(nil, chapter, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("FetchChapter() returned %d files, want 2", len(files))
+	}
+	if !bytes.Equal(files[0].Data, image) {
+		t.Errorf("FetchChapter() page 1 = %q, want the decrypted image %q", files[0].Data, image)
+	}
+	if !bytes.Equal(files[1].Data, encrypted) {
+		t.Errorf("FetchChapter() page 2 = %q, want the image as served", files[1].Data)
+	}
+}
+
+// localSite serves its pages without HTTP, like a source reading files on disk
+type localSite struct {
+	MockGrabber
+	pages map[string][]byte
+}
+
+func (l *localSite) FetchPage(page grabber.Page) ([]byte, error) {
+	data, ok := l.pages[page.URL]
+	if !ok {
+		return nil, fmt.Errorf("no such file: %s", page.URL)
+	}
+	return data, nil
+}
+
+func TestFetchChapter_PageFetcher(t *testing.T) {
+	site := &localSite{pages: map[string][]byte{
+		"file:///manga/1/001.jpg": []byte("page 1"),
+		"file:///manga/1/002.jpg": []byte("page 2"),
+	}}
+
+	chapter := &grabber.Chapter{
+		Number: 1,
+		Pages: []grabber.Page{
+			{Number: 2, URL: "file:///manga/1/002.jpg"},
+			{Number: 1, URL: "file:///manga/1/001.jpg"},
+		},
+	}
+
+	files, err := FetchChapter(site, chapter, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+	if len(files) != 2 || string(files[0].Data) != "page 1" || string(files[1].Data) != "page 2" {
+		t.Errorf("FetchChapter() = %v, want the pages read by the site in order", files)
+	}
+
+	chapter.Pages = append(chapter.Pages, grabber.Page{Number: 3, URL: "file:///manga/1/003.jpg"})
+	if _, err := FetchChapter(site, chapter, func(int, int, error) {}); err == nil {
+		t.Error("FetchChapter() error = nil for a missing file")
+	}
+}
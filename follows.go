@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.sammcclenaghan.com/mango/colors"
+	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/packer"
+)
+
+// defaultFollowsWindow is how far back the followed feed is read the first time
+const defaultFollowsWindow = 7 * 24 * time.Hour
+
+// followsState is stored between runs of the follows subcommand
+type followsState struct {
+	LastCheck time.Time `json:"last_check"`
+	// Failed holds the series whose new chapters could not be downloaded by URL, with the date their chapters are
+	// read from again on the next run
+	Failed map[string]time.Time `json:"failed,omitempty"`
+}
+
+// runFollows handles the follows subcommand: it downloads the chapters uploaded since the last run to the series the
+// user follows, one output directory per series
+func runFollows(argv []string) (string, error) {
+	args, err := parseArgs(argv)
+	if err != nil {
+		return "", err
+	}
+
+	opts := args.Options
+	if len(opts.Settings.LanguagePriority()) == 0 {
+		opts.Settings.Language = "en" // default to English
+	}
+	opts.Settings.Auth, err = loginSession()
+	if err != nil {
+		return "", err
+	}
+
+	site, err := grabber.NewSite("mangadex", &grabber.Grabber{Settings: opts.Settings})
+	if err != nil {
+		return "", err
+	}
+	fetcher, ok := site.(grabber.FollowsFetcher)
+	if !ok {
+		return "", fmt.Errorf("site mangadex does not support follows")
+	}
+
+	statePath, err := followsStatePath()
+	if err != nil {
+		return "", err
+	}
+	state := loadFollowsState(statePath)
+
+	now := time.Now()
+	since := now.Add(-defaultFollowsWindow)
+	if args.since != "" {
+		if since, err = parseSince(args.since, now); err != nil {
+			return "", err
+		}
+	} else if !state.LastCheck.IsZero() {
+		since = state.LastCheck
+	}
+
+	openSite := func(url string) (grabber.GrabberInterface, error) {
+		return grabber.New(&grabber.Grabber{URL: url, Settings: opts.Settings})
+	}
+	output, failed, err := fetchFollows(fetcher, since, state.Failed, opts, openSite)
+
+	// listing doesn't consume the new chapters, they are downloaded on the next run. A failing series keeps its date
+	// so the others move on.
+	if failed != nil && !opts.ListOnly {
+		state.LastCheck = now
+		state.Failed = failed
+		if err := saveFollowsState(statePath, state); err != nil {
+			colors.WarningPrintf("Warning: error saving follows state: %v\n", err)
+		}
+	}
+
+	return output, err
+}
+
+// fetchFollows downloads the new chapters of the followed series, using the usual language and group rules. The series
+// that failed on previous runs are read again from the date they failed at. A series that fails doesn't stop the
+// others, an error listing the failed series is returned once all were tried, along with the date each of them is to
+// be read from next time. The failed series are nil only when the followed feed couldn't be read.
+func fetchFollows(fetcher grabber.FollowsFetcher, since time.Time, pending map[string]time.Time, opts Options, openSite func(url string) (grabber.GrabberInterface, error)) (string, map[string]time.Time, error) {
+	followed, err := fetcher.FetchFollowedChapters(since)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching followed feed: %w", err)
+	}
+
+	// series dropped from the feed since they failed, like unfollowed ones, are forgotten
+	oldest := since
+	for _, date := range pending {
+		if date.Before(oldest) {
+			oldest = date
+		}
+	}
+	if oldest.Before(since) {
+		older, err := fetcher.FetchFollowedChapters(oldest)
+		if err != nil {
+			return "", nil, fmt.Errorf("error fetching followed feed: %w", err)
+		}
+		followed = retryFailed(followed, older, pending)
+	}
+
+	output := fmt.Sprintf("Found %d followed series with new chapters since %s\n", len(followed), since.Local().Format("2006-01-02 15:04"))
+
+	if !opts.ListOnly {
+		opts.Download = true
+		opts.SaveCBZ = true
+	}
+	baseDir := opts.OutputDir
+
+	var failedTitles []string
+	failed := make(map[string]time.Time)
+	fail := func(series grabber.FollowedSeries) {
+		failedTitles = append(failedTitles, series.Title)
+		failed[series.URL] = since
+		if date, ok := pending[series.URL]; ok && date.Before(since) {
+			failed[series.URL] = date
+		}
+	}
+
+	for _, series := range followed {
+		output += "\n"
+
+		site, err := openSite(series.URL)
+		if err != nil {
+			colors.ErrorPrintf("Error opening %s: %v\n", series.Title, err)
+			fail(series)
+			continue
+		}
+
+		opts.OutputDir = filepath.Join(baseDir, packer.GetSeriesDirname(series.Title))
+		content, err := fetchChapterRange(site, series.Chapters, series.Title, opts)
+		if err != nil {
+			colors.ErrorPrintf("Error downloading %s: %v\n", series.Title, err)
+			fail(series)
+			continue
+		}
+		output += content
+	}
+
+	if len(failedTitles) > 0 {
+		return output, failed, fmt.Errorf("could not download the new chapters of: %s", strings.Join(failedTitles, ", "))
+	}
+
+	return output, failed, nil
+}
+
+// retryFailed replaces the followed series which failed on previous runs with their chapters from the older feed, so
+// the chapters they missed are downloaded too
+func retryFailed(followed, older []grabber.FollowedSeries, pending map[string]time.Time) []grabber.FollowedSeries {
+	index := make(map[string]int, len(followed))
+	for i, series := range followed {
+		index[series.URL] = i
+	}
+
+	for _, series := range older {
+		if _, ok := pending[series.URL]; !ok {
+			continue
+		}
+		if i, ok := index[series.URL]; ok {
+			followed[i] = series
+		} else {
+			followed = append(followed, series)
+		}
+	}
+
+	return followed
+}
+
+// parseSince parses a --since value, either a date like 2024-01-31 or a number of days like 7d
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since value '%s': use a date (2024-01-31) or a number of days (7d)", value)
+}
+
+// followsStatePath returns the follows state file in the user config directory
+func followsStatePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "mango", "follows.json"), nil
+}
+
+// loadFollowsState reads the follows state, a missing or invalid file is the state of the first run
+func loadFollowsState(path string) followsState {
+	state := followsState{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			colors.WarningPrintf("Warning: error reading follows state: %v\n", err)
+		}
+		return state
+	}
+
+	if err = json.Unmarshal(data, &state); err != nil {
+		colors.WarningPrintf("Warning: invalid follows state %s: %v\n", path, err)
+	}
+	return state
+}
+
+// saveFollowsState stores the follows state
+func saveFollowsState(path string, state followsState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+// fakeFollows is a FollowsFetcher returning fixed series, the ones with an upload date only when it's after since
+type fakeFollows struct {
+	followed []grabber.FollowedSeries
+	uploaded map[string]time.Time
+	since    time.Time
+}
+
+func (f *fakeFollows) FetchFollowedChapters(since time.Time) ([]grabber.FollowedSeries, error) {
+	f.since = since
+	var followed []grabber.FollowedSeries
+	for _, series := range f.followed {
+		if date, ok := f.uploaded[series.URL]; !ok || !date.Before(since) {
+			followed = append(followed, series)
+		}
+	}
+	return followed, nil
+}
+
+func TestFetchFollows(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer ts.Close()
+
+	fetcher := &fakeFollows{followed: []grabber.FollowedSeries{
+		{Title: "First", URL: "https://example.com/first", Chapters: grabber.Filterables{
+			&grabber.Chapter{Number: 3, Label: "3", Title: "Three", Language: "en", Groups: []string{"Bad Group"}},
+			&grabber.Chapter{Number: 3, Label: "3", Title: "Three", Language: "en", Groups: []string{"Good Group"}},
+			&grabber.Chapter{Number: 4, Label: "4", Title: "Four", Language: "en"},
+		}},
+		{Title: "Second: Part 2", URL: "https://example.com/second", Chapters: grabber.Filterables{
+			&grabber.Chapter{Number: 10, Label: "10", Title: "Ten", Language: "en"},
+		}},
+		{Title: "Broken", URL: "https://example.com/broken", Chapters: grabber.Filterables{
+			&grabber.Chapter{Number: 1, Label: "1", Language: "en"},
+		}},
+	}}
+
+	var opened []string
+	openSite := func(url string) (grabber.GrabberInterface, error) {
+		opened = append(opened, url)
+		if strings.HasSuffix(url, "broken") {
+			return nil, &grabber.UnsupportedSiteError{URL: url}
+		}
+		return &fakeSite{pagesURL: ts.URL}, nil
+	}
+
+	outputDir := t.TempDir()
+	since := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+	opts := Options{OutputDir: outputDir, Settings: grabber.Settings{BlockedGroups: []string{"Bad Group"}}}
+
+	output, failed, err := fetchFollows(fetcher, since, nil, opts, openSite)
+	if err == nil || !strings.Contains(err.Error(), "Broken") {
+		t.Errorf("fetchFollows() error = %v, want the failed series listed", err)
+	}
+	if len(failed) != 1 || !failed["https://example.com/broken"].Equal(since) {
+		t.Errorf("fetchFollows() failed = %v, want the broken series kept at %v", failed, since)
+	}
+
+	if !fetcher.since.Equal(since) {
+		t.Errorf("followed feed read since %v, want %v", fetcher.since, since)
+	}
+	if len(opened) != 3 {
+		t.Errorf("opened series %v, want every series tried", opened)
+	}
+
+	if !strings.Contains(output, "Found 3 followed series") || !strings.Contains(output, "Found 2 unique chapters in all chapters") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	for _, file := range []string{
+		filepath.Join("First", "First - Chapter 0 - Chapters 3-4.cbz"),
+		filepath.Join("Second_ Part 2", "Second_ Part 2 - Chapter 10 - Ten.cbz"),
+	} {
+		if _, err := os.Stat(filepath.Join(outputDir, file)); err != nil {
+			entries, _ := filepath.Glob(filepath.Join(outputDir, "*", "*"))
+			t.Errorf("expected %s to be created: %v (found %v)", file, err, entries)
+		}
+	}
+}
+
+func TestFetchFollows_Failed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer ts.Close()
+
+	firstRun := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+	secondRun := firstRun.AddDate(0, 0, 7)
+	fetcher := &fakeFollows{
+		followed: []grabber.FollowedSeries{
+			{Title: "Working", URL: "https://example.com/working", Chapters: grabber.Filterables{
+				&grabber.Chapter{Number: 1, Label: "1", Language: "en"},
+			}},
+			{Title: "Broken", URL: "https://example.com/broken", Chapters: grabber.Filterables{
+				&grabber.Chapter{Number: 1, Label: "1", Language: "en"},
+			}},
+		},
+		// both series were uploaded to between the runs
+		uploaded: map[string]time.Time{
+			"https://example.com/working": firstRun.AddDate(0, 0, 1),
+			"https://example.com/broken":  firstRun.AddDate(0, 0, 1),
+		},
+	}
+
+	var opened []string
+	openSite := func(url string) (grabber.GrabberInterface, error) {
+		opened = append(opened, url)
+		if strings.HasSuffix(url, "broken") {
+			return nil, &grabber.UnsupportedSiteError{URL: url}
+		}
+		return &fakeSite{pagesURL: ts.URL}, nil
+	}
+	opts := Options{OutputDir: t.TempDir()}
+
+	_, failed, err := fetchFollows(fetcher, firstRun, nil, opts, openSite)
+	if err == nil || len(failed) != 1 {
+		t.Fatalf("fetchFollows() = %v, %v, want the broken series failed", failed, err)
+	}
+
+	// the next run reads the feed from its own date, only the broken series goes back to the first one
+	opened = nil
+	_, failed, err = fetchFollows(fetcher, secondRun, failed, opts, openSite)
+	if err == nil {
+		t.Error("fetchFollows() error = nil, want the broken series to fail again")
+	}
+	if strings.Join(opened, ",") != "https://example.com/broken" {
+		t.Errorf("opened series %v on the next run, want only the broken one", opened)
+	}
+	if !failed["https://example.com/broken"].Equal(firstRun) {
+		t.Errorf("fetchFollows() failed = %v, want the broken series kept at %v", failed, firstRun)
+	}
+
+	// a series gone from the feed, like an unfollowed one, is forgotten
+	opened = nil
+	fetcher.followed = fetcher.followed[:1]
+	if _, failed, err = fetchFollows(fetcher, secondRun, failed, opts, openSite); err != nil || len(failed) != 0 || len(opened) != 0 {
+		t.Errorf("fetchFollows() = %v, %v after opening %v, want nothing left to retry", failed, err, opened)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 2, 10, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{value: "7d", expected: time.Date(2024, 2, 3, 15, 0, 0, 0, time.Local)},
+		{value: "2024-01-31", expected: time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)},
+		{value: "yesterday", wantErr: true},
+		{value: "-2d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		result, err := parseSince(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSince(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !result.Equal(tt.expected) {
+			t.Errorf("parseSince(%q) = %v, want %v", tt.value, result, tt.expected)
+		}
+	}
+}
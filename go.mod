@@ -1,3 +1,7 @@
 module github.sammcclenaghan.com/mango
 
 go 1.24.3
+
+require golang.org/x/term v0.40.0
+
+require golang.org/x/sys v0.41.0 // indirect
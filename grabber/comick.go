@@ -0,0 +1,248 @@
+package grabber
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/http"
+)
+
+// Comick is a grabber for comick.io
+type Comick struct {
+	*Grabber
+	// ApiUrl is the base URL of the Comick API
+	ApiUrl string
+	// ImagesUrl is the image server pages are downloaded from
+	ImagesUrl string
+	// chapterLimit is the number of chapters requested per page of the chapter list
+	chapterLimit int
+	comic        *comickComic
+}
+
+func init() {
+	Register("Comick", comickUrlRe.MatchString, func(g *Grabber) GrabberInterface {
+		return NewComick(g)
+	})
+}
+
+// comickApiUrl is the default base URL of the Comick API
+const comickApiUrl = "https://api.comick.io"
+
+// comickImagesUrl is the default image server
+const comickImagesUrl = "https://meo.comick.pictures"
+
+// comickChapterLimit is the largest page of chapters the API returns
+const comickChapterLimit = 300
+
+// comickOneshotLabel labels chapters without a chapter number
+const comickOneshotLabel = "Oneshot"
+
+// comickUrlRe matches Comick comic URLs, capturing the slug of the comic
+var comickUrlRe = regexp.MustCompile(`comick\.(?:io|fun|cc)/comic/([^/?#]+)`)
+
+func NewComick(g *Grabber) *Comick {
+	return &Comick{
+		Grabber:      g,
+		ApiUrl:       comickApiUrl,
+		ImagesUrl:    comickImagesUrl,
+		chapterLimit: comickChapterLimit,
+	}
+}
+
+// ComickChapter represents a Comick chapter
+type ComickChapter struct {
+	Chapter
+	Id string
+}
+
+// GetId returns the Comick id of the chapter
+func (c ComickChapter) GetId() string {
+	return c.Id
+}
+
+// Test checks if the URL is a Comick comic
+func (c *Comick) Test() (bool, error) {
+	return comickUrlRe.MatchString(c.URL), nil
+}
+
+// FetchTitle returns the title of the comic
+func (c *Comick) FetchTitle() (string, error) {
+	comic, err := c.fetchComic()
+	if err != nil {
+		return "", err
+	}
+	return comic.Title, nil
+}
+
+// FetchChapters returns the chapters of the comic in the requested languages, reading the chapter list page by page.
+// A page failing keeps the chapters of the previous ones.
+func (c *Comick) FetchChapters() (chapters Filterables, errs []error) {
+	comic, err := c.fetchComic()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(c.chapterLimit))
+	if languages := c.Settings.LanguagePriority(); len(languages) > 0 {
+		params.Set("lang", strings.Join(languages, ","))
+	}
+
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+
+		var list comickChapterList
+		err := c.get(fmt.Sprintf("%s/comic/%s/chapters?%s", c.ApiUrl, comic.Hid, params.Encode()), &list)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		for _, ch := range list.Chapters {
+			chapters = append(chapters, ch.chapter())
+		}
+
+		// the total may be missing, then the list ends with an empty page
+		if len(list.Chapters) == 0 || (list.Total > 0 && len(chapters) >= list.Total) {
+			break
+		}
+	}
+
+	SortChapters(chapters)
+	return
+}
+
+// FetchChapter fetches the images of a chapter
+func (c *Comick) FetchChapter(f Filterable) (*Chapter, error) {
+	chap := f.(*ComickChapter)
+
+	var body struct {
+		Chapter struct {
+			MdImages []struct {
+				B2key string
+			} `json:"md_images"`
+		}
+	}
+	if err := c.get(fmt.Sprintf("%s/chapter/%s/", c.ApiUrl, chap.Id), &body); err != nil {
+		return nil, err
+	}
+
+	chapter := &Chapter{
+		Title:    chap.Title,
+		Number:   chap.Number,
+		Label:    chap.Label,
+		Volume:   chap.Volume,
+		Language: chap.Language,
+		Groups:   chap.Groups,
+		id:       chap.Id,
+	}
+	for _, image := range body.Chapter.MdImages {
+		chapter.Pages = append(chapter.Pages, Page{
+			Number: int64(len(chapter.Pages) + 1),
+			URL:    c.ImagesUrl + "/" + image.B2key,
+		})
+	}
+
+	if len(chapter.Pages) == 0 {
+		return nil, fmt.Errorf("no images found for chapter %s", ChapterName(chap))
+	}
+	chapter.PagesCount = int64(len(chapter.Pages))
+
+	return chapter, nil
+}
+
+// fetchComic returns the comic of the URL, it's requested once and shared by FetchTitle and FetchChapters
+func (c *Comick) fetchComic() (*comickComic, error) {
+	if c.comic != nil {
+		return c.comic, nil
+	}
+
+	match := comickUrlRe.FindStringSubmatch(c.URL)
+	if match == nil {
+		return nil, fmt.Errorf("%s is not a Comick comic URL", c.URL)
+	}
+
+	var body struct {
+		Comic comickComic
+	}
+	if err := c.get(fmt.Sprintf("%s/comic/%s/", c.ApiUrl, match[1]), &body); err != nil {
+		return nil, err
+	}
+	if body.Comic.Hid == "" {
+		return nil, fmt.Errorf("no comic found at %s", c.URL)
+	}
+
+	c.comic = &body.Comic
+	return c.comic, nil
+}
+
+// get requests an API endpoint, decoding its JSON response into v
+func (c *Comick) get(uri string, v interface{}) error {
+	rbody, err := http.Get(http.RequestParams{
+		URL:     uri,
+		Referer: "https://comick.io/",
+	})
+	if err != nil {
+		return err
+	}
+	defer rbody.Close()
+
+	return json.NewDecoder(rbody).Decode(v)
+}
+
+// comickComic represents the comic json object
+type comickComic struct {
+	Hid   string
+	Slug  string
+	Title string
+}
+
+// comickChapterList represents a page of the chapter list
+type comickChapterList struct {
+	Chapters []comickChapter
+	Total    int
+}
+
+// comickChapter represents a chapter of the chapter list, chapter and volume numbers are null when missing
+type comickChapter struct {
+	Hid       string
+	Chap      *string
+	Vol       *string
+	Title     *string
+	Lang      string
+	GroupName []string `json:"group_name"`
+}
+
+// chapter returns the chapter of the list entry
+func (c comickChapter) chapter() *ComickChapter {
+	// non-numeric chapters keep their label, chapters without one are oneshots
+	label := strings.TrimSpace(comickString(c.Chap))
+	if label == "" {
+		label = comickOneshotLabel
+	}
+	num, _ := strconv.ParseFloat(label, 64)
+
+	return &ComickChapter{
+		Chapter: Chapter{
+			Number:   num,
+			Label:    label,
+			Volume:   comickString(c.Vol),
+			Title:    comickString(c.Title),
+			Language: c.Lang,
+			Groups:   c.GroupName,
+		},
+		Id: c.Hid,
+	}
+}
+
+// comickString returns the string pointed to, or "" for null JSON values
+func comickString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,189 @@
+package grabber
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// comickServer serves a comic with five chapters, listed two per page
+func comickServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	str := func(s string) *string { return &s }
+	chapters := []comickChapter{
+		{Hid: "c5", Chap: str("3"), Vol: str("1"), Title: str("Three"), Lang: "en", GroupName: []string{"Alpha Scans"}},
+		{Hid: "c4", Chap: str("2.5"), Lang: "en", GroupName: []string{"Alpha Scans"}},
+		{Hid: "c3", Chap: str("2"), Title: str("Two"), Lang: "en", GroupName: []string{"Zeta Scans"}},
+		{Hid: "c2", Chap: str("1"), Title: str("One"), Lang: "en"},
+		{Hid: "c1", Title: str("Pilot"), Lang: "en"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/comic/sample-comic/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"comic": map[string]string{"hid": "abc123", "slug": "sample-comic", "title": "Sample Comic"},
+		})
+	})
+	mux.HandleFunc("/comic/abc123/chapters", func(w http.ResponseWriter, r *http.Request) {
+		if lang := r.URL.Query().Get("lang"); lang != "en,es" {
+			t.Errorf("chapters requested with lang %q, want en,es", lang)
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min((page-1)*limit, len(chapters))
+		end := min(start+limit, len(chapters))
+		json.NewEncoder(w).Encode(comickChapterList{Chapters: chapters[start:end], Total: len(chapters)})
+	})
+	mux.HandleFunc("/chapter/c3/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"chapter": {"hid": "c3", "md_images": [{"b2key": "0001-a.jpg", "w": 800}, {"b2key": "0002-b.jpg", "w": 800}]}}`))
+	})
+	mux.HandleFunc("/chapter/c4/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"chapter": {"hid": "c4", "md_images": []}}`))
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestComick(ts *httptest.Server) *Comick {
+	c := NewComick(&Grabber{URL: "https://comick.io/comic/sample-comic", Settings: Settings{Languages: []string{"en", "es"}}})
+	c.ApiUrl = ts.URL
+	c.ImagesUrl = "https://images.example.com"
+	c.chapterLimit = 2
+	return c
+}
+
+func TestComick_Test(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "https://comick.io/comic/sample-comic", expected: true},
+		{url: "https://comick.io/comic/sample-comic?lang=en", expected: true},
+		{url: "https://comick.io/search?q=sample", expected: false},
+		{url: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f", expected: false},
+	}
+
+	for _, tt := range tests {
+		if ok, _ := NewComick(&Grabber{URL: tt.url}).Test(); ok != tt.expected {
+			t.Errorf("Test(%s) = %v, want %v", tt.url, ok, tt.expected)
+		}
+	}
+}
+
+func TestComick_FetchChapters(t *testing.T) {
+	c := newTestComick(comickServer(t))
+
+	title, err := c.FetchTitle()
+	if err != nil {
+		t.Fatalf("FetchTitle() error = %v", err)
+	}
+	if title != "Sample Comic" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "Sample Comic")
+	}
+
+	chapters, errs := c.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+
+	var labels, ids []string
+	for _, ch := range chapters {
+		labels = append(labels, ch.GetLabel())
+		ids = append(ids, ch.GetId())
+	}
+	if expected := []string{"1", "2", "2.5", "3", "Oneshot"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("FetchChapters() labels = %v, want %v", labels, expected)
+	}
+	if expected := []string{"c2", "c3", "c4", "c5", "c1"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("FetchChapters() ids = %v, want %v", ids, expected)
+	}
+
+	third := chapters[3]
+	if third.GetVolume() != "1" || third.GetTitle() != "Three" || !reflect.DeepEqual(third.GetGroups(), []string{"Alpha Scans"}) {
+		t.Errorf("FetchChapters() chapter 3 = %+v, want its volume, title and group", third)
+	}
+}
+
+func TestComick_FetchChapters_Pages(t *testing.T) {
+	str := func(s string) *string { return &s }
+	chapters := []comickChapter{
+		{Hid: "c3", Chap: str("3"), Lang: "en"},
+		{Hid: "c2", Chap: str("2"), Lang: "en"},
+		{Hid: "c1", Chap: str("1"), Lang: "en"},
+	}
+
+	tests := []struct {
+		name     string
+		total    int
+		broken   int
+		expected []string
+		errors   int
+	}{
+		{name: "without total", expected: []string{"c1", "c2", "c3"}},
+		{name: "failing page", total: len(chapters), broken: 2, expected: []string{"c2", "c3"}, errors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/comic/sample-comic/", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"comic": {"hid": "abc123", "slug": "sample-comic", "title": "Sample Comic"}}`))
+			})
+			mux.HandleFunc("/comic/abc123/chapters", func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				if page == tt.broken {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				start := min((page-1)*2, len(chapters))
+				end := min(start+2, len(chapters))
+				json.NewEncoder(w).Encode(comickChapterList{Chapters: chapters[start:end], Total: tt.total})
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			result, errs := newTestComick(ts).FetchChapters()
+			if len(errs) != tt.errors {
+				t.Errorf("FetchChapters() errors = %v, want %d", errs, tt.errors)
+			}
+
+			var ids []string
+			for _, ch := range result {
+				ids = append(ids, ch.GetId())
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("FetchChapters() ids = %v, want %v", ids, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComick_FetchChapter(t *testing.T) {
+	c := newTestComick(comickServer(t))
+
+	chapter, err := c.FetchChapter(&ComickChapter{Chapter: Chapter{Number: 2, Label: "2", Groups: []string{"Zeta Scans"}}, Id: "c3"})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	expected := []Page{
+		{Number: 1, URL: "https://images.example.com/0001-a.jpg"},
+		{Number: 2, URL: "https://images.example.com/0002-b.jpg"},
+	}
+	if !reflect.DeepEqual(chapter.Pages, expected) {
+		t.Errorf("FetchChapter() pages = %+v, want %+v", chapter.Pages, expected)
+	}
+	if chapter.PagesCount != 2 || chapter.GetId() != "c3" || chapter.Label != "2" {
+		t.Errorf("FetchChapter() = %+v, want chapter 2 with its id and 2 pages", chapter)
+	}
+
+	if _, err := c.FetchChapter(&ComickChapter{Chapter: Chapter{Number: 2.5, Label: "2.5"}, Id: "c4"}); err == nil {
+		t.Error("FetchChapter() error = nil for a chapter without images")
+	}
+}
@@ -0,0 +1,342 @@
+package grabber
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Local is a source reading chapters already on disk, so old downloads can be packed again. Every image folder
+// and CBZ or ZIP archive in the directory of the URL is a chapter.
+type Local struct {
+	*Grabber
+	// archived holds the images FetchChapter read from the archive of the last chapter by page URL, FetchPage hands
+	// each out once
+	archived map[string][]byte
+	mu       sync.Mutex
+}
+
+func init() {
+	Register("Local", isLocalPath, func(g *Grabber) GrabberInterface {
+		return NewLocal(g)
+	})
+}
+
+var (
+	localChapterRe = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:chapter|ch|c)\.?\s*(\d+(?:\.\d+)?)(?:\s+-\s+(.+))?`)
+	localVolumeRe  = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:volume|vol|v)\.?\s*(\d+(?:\.\d+)?)`)
+	localNumberRe  = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	localDigitsRe  = regexp.MustCompile(`\d+|\D+`)
+)
+
+// localImageExtensions are the extensions of the files read as pages
+var localImageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true,
+}
+
+// localCoverFilename is the cover mango adds to the archives it packs, it's not a page of the chapter
+const localCoverFilename = "000_cover.jpg"
+
+// isLocalPath reports whether the URL is a file:// URL or the path of a directory
+func isLocalPath(u string) bool {
+	if strings.HasPrefix(u, "file://") {
+		return true
+	}
+	if strings.Contains(u, "://") {
+		return false
+	}
+	info, err := os.Stat(u)
+	return err == nil && info.IsDir()
+}
+
+func NewLocal(g *Grabber) *Local {
+	return &Local{Grabber: g}
+}
+
+// LocalChapter represents a chapter folder or archive
+type LocalChapter struct {
+	Chapter
+	Path string
+}
+
+// GetId returns the path of the chapter, which identifies it on disk
+func (c LocalChapter) GetId() string {
+	return c.Path
+}
+
+// Test checks if the URL is a directory
+func (l *Local) Test() (bool, error) {
+	info, err := os.Stat(l.dir())
+	if err != nil {
+		return false, nil
+	}
+	return info.IsDir(), nil
+}
+
+// FetchTitle returns the name of the directory
+func (l *Local) FetchTitle() (string, error) {
+	return filepath.Base(filepath.Clean(l.dir())), nil
+}
+
+// FetchChapters returns the image folders and archives of the directory, with their chapter and volume numbers
+// parsed from their names. Loose images in the directory itself are ignored.
+func (l *Local) FetchChapters() (chapters Filterables, errs []error) {
+	entries, err := os.ReadDir(l.dir())
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(l.dir(), entry.Name())
+		name := entry.Name()
+
+		if !entry.IsDir() {
+			ext := strings.ToLower(filepath.Ext(name))
+			if ext != ".cbz" && ext != ".zip" {
+				continue
+			}
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		} else if images, err := localImages(path); err != nil {
+			errs = append(errs, err)
+			continue
+		} else if len(images) == 0 {
+			continue
+		}
+
+		chapters = append(chapters, &LocalChapter{Chapter: parseLocalName(name), Path: path})
+	}
+
+	SortChapters(chapters)
+	return
+}
+
+// parseLocalName returns the chapter described by the name of a folder or archive, like "Title - Chapter 12 - Name"
+// or "Title v02 c012". Names without a chapter number use their last number, names without any number are labels.
+func parseLocalName(name string) Chapter {
+	chapter := Chapter{}
+
+	rest := name
+	if match := localVolumeRe.FindStringSubmatchIndex(name); match != nil {
+		chapter.Volume = trimNumber(name[match[2]:match[3]])
+		rest = name[:match[0]] + " " + name[match[1]:]
+	}
+
+	number := ""
+	if match := localChapterRe.FindStringSubmatch(rest); match != nil {
+		number = match[1]
+		chapter.Title = strings.TrimSpace(match[2])
+	} else if numbers := localNumberRe.FindAllString(rest, -1); numbers != nil {
+		number = numbers[len(numbers)-1]
+	}
+
+	if number == "" {
+		chapter.Label = strings.TrimSpace(name)
+		return chapter
+	}
+
+	chapter.Number, _ = strconv.ParseFloat(number, 64)
+	chapter.Label = strconv.FormatFloat(chapter.Number, 'f', -1, 64)
+	return chapter
+}
+
+// trimNumber drops the leading zeros of a number, "02" is volume "2"
+func trimNumber(number string) string {
+	if num, err := strconv.ParseFloat(number, 64); err == nil {
+		return strconv.FormatFloat(num, 'f', -1, 64)
+	}
+	return number
+}
+
+// FetchChapter lists the images of a chapter folder or archive in natural order, pages point to their files with
+// file:// URLs which FetchPage reads. Archives are read whole here, so they're opened once per chapter.
+func (l *Local) FetchChapter(f Filterable) (*Chapter, error) {
+	chap := f.(*LocalChapter)
+
+	var pages []string
+	info, err := os.Stat(chap.Path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		images, err := localImages(chap.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			pages = append(pages, (&url.URL{Scheme: "file", Path: filepath.Join(chap.Path, image)}).String())
+		}
+	} else {
+		images, data, err := localArchiveImages(chap.Path)
+		if err != nil {
+			return nil, err
+		}
+		archived := make(map[string][]byte, len(images))
+		for i, image := range images {
+			page := (&url.URL{Scheme: "file", Path: chap.Path, Fragment: image}).String()
+			pages = append(pages, page)
+			archived[page] = data[i]
+		}
+
+		l.mu.Lock()
+		l.archived = archived
+		l.mu.Unlock()
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no images found in %s", chap.Path)
+	}
+
+	chapter := &Chapter{
+		Title:    chap.Title,
+		Number:   chap.Number,
+		Label:    chap.Label,
+		Volume:   chap.Volume,
+		Language: chap.Language,
+		id:       chap.Path,
+	}
+	for _, page := range pages {
+		chapter.Pages = append(chapter.Pages, Page{Number: int64(len(chapter.Pages) + 1), URL: page})
+	}
+	chapter.PagesCount = int64(len(chapter.Pages))
+
+	return chapter, nil
+}
+
+// FetchPage reads the image of a page, from its file or from the archive entry named by the URL fragment
+func (l *Local) FetchPage(page Page) ([]byte, error) {
+	u, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("page %d is not a local file: %s", page.Number, page.URL)
+	}
+
+	if u.Fragment == "" {
+		return os.ReadFile(u.Path)
+	}
+
+	l.mu.Lock()
+	data, ok := l.archived[page.URL]
+	delete(l.archived, page.URL)
+	l.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	// pages of another chapter than the last one fetched are read from their archive
+	archive, err := zip.OpenReader(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	entry, err := archive.Open(u.Fragment)
+	if err != nil {
+		return nil, err
+	}
+	defer entry.Close()
+
+	return io.ReadAll(entry)
+}
+
+// dir returns the directory of the URL
+func (l *Local) dir() string {
+	if u, err := url.Parse(l.URL); err == nil && u.Scheme == "file" {
+		return u.Path
+	}
+	return l.URL
+}
+
+// localImages returns the names of the images of a folder in natural order
+func localImages(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isLocalImage(entry.Name()) {
+			images = append(images, entry.Name())
+		}
+	}
+
+	sortNatural(images)
+	return images, nil
+}
+
+// localArchiveImages returns the names of the images of an archive in natural order, with their content
+func localArchiveImages(path string) ([]string, [][]byte, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File)
+	var images []string
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() && isLocalImage(file.Name) {
+			images = append(images, file.Name)
+			files[file.Name] = file
+		}
+	}
+	sortNatural(images)
+
+	data := make([][]byte, len(images))
+	for i, image := range images {
+		if data[i], err = readZipFile(files[image]); err != nil {
+			return nil, nil, fmt.Errorf("error reading %s from %s: %w", image, path, err)
+		}
+	}
+
+	return images, data, nil
+}
+
+// readZipFile returns the content of an archive entry
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// isLocalImage reports whether a file is a page image
+func isLocalImage(name string) bool {
+	base := filepath.Base(name)
+	if base == localCoverFilename || strings.HasPrefix(base, ".") {
+		return false
+	}
+	return localImageExtensions[strings.ToLower(filepath.Ext(base))]
+}
+
+// sortNatural sorts names comparing their numbers by value, so "2.jpg" comes before "10.jpg"
+func sortNatural(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := localDigitsRe.FindAllString(names[i], -1), localDigitsRe.FindAllString(names[j], -1)
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] == b[k] {
+				continue
+			}
+			x, errX := strconv.Atoi(a[k])
+			y, errY := strconv.Atoi(b[k])
+			if errX == nil && errY == nil && x != y {
+				return x < y
+			}
+			return a[k] < b[k]
+		}
+		return len(a) < len(b)
+	})
+}
@@ -0,0 +1,194 @@
+package grabber
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// localLibrary creates a directory with two chapter folders, a CBZ archive and files which are not chapters
+func localLibrary(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "Old Manga")
+	write := func(name string, data string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("Old Manga v01 c001/10.jpg", "page 10")
+	write("Old Manga v01 c001/2.jpg", "page 2")
+	write("Old Manga v01 c001/1.png", "page 1")
+	write("Old Manga v01 c001/notes.txt", "not a page")
+	write("Extra/1.jpg", "extra page")
+	write("empty/notes.txt", "no pages")
+	write("cover.jpg", "loose image")
+	write("readme.txt", "not a chapter")
+
+	archive, err := os.Create(filepath.Join(dir, "Old Manga - Chapter 2.5 - Side Story.cbz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(archive)
+	for _, entry := range []struct{ name, data string }{
+		{"000_cover.jpg", "cover"},
+		{"002.jpg", "archived page 2"},
+		{"001.jpg", "archived page 1"},
+		{"ComicInfo.xml", "<ComicInfo/>"},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(entry.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	return dir
+}
+
+func TestParseLocalName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Chapter
+	}{
+		{name: "One Piece - Chapter 12 - Romance Dawn", expected: Chapter{Number: 12, Label: "12", Title: "Romance Dawn"}},
+		{name: "One Piece - Chapter 12.5", expected: Chapter{Number: 12.5, Label: "12.5"}},
+		{name: "One Piece v02 c010", expected: Chapter{Number: 10, Label: "10", Volume: "2"}},
+		{name: "Vol.3 Ch.21", expected: Chapter{Number: 21, Label: "21", Volume: "3"}},
+		{name: "015", expected: Chapter{Number: 15, Label: "15"}},
+		{name: "Kaiju No. 8 - 003", expected: Chapter{Number: 3, Label: "3"}},
+		{name: "Volume 4", expected: Chapter{Label: "Volume 4", Volume: "4"}},
+		{name: "Extra", expected: Chapter{Label: "Extra"}},
+	}
+
+	for _, tt := range tests {
+		if chapter := parseLocalName(tt.name); !reflect.DeepEqual(chapter, tt.expected) {
+			t.Errorf("parseLocalName(%q) = %+v, want %+v", tt.name, chapter, tt.expected)
+		}
+	}
+}
+
+func TestLocal_FetchChapters(t *testing.T) {
+	dir := localLibrary(t)
+
+	for _, u := range []string{dir, "file://" + dir} {
+		site, err := New(&Grabber{URL: u})
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", u, err)
+		}
+		if _, ok := site.(*Local); !ok {
+			t.Fatalf("New(%s) = %T, want *Local", u, site)
+		}
+
+		title, err := site.FetchTitle()
+		if err != nil || title != "Old Manga" {
+			t.Errorf("FetchTitle() = %q, %v, want the directory name", title, err)
+		}
+
+		chapters, errs := site.FetchChapters()
+		if len(errs) > 0 {
+			t.Fatalf("FetchChapters() errors = %v", errs)
+		}
+
+		var labels []string
+		for _, ch := range chapters {
+			labels = append(labels, ch.GetLabel())
+		}
+		if expected := []string{"1", "2.5", "Extra"}; !reflect.DeepEqual(labels, expected) {
+			t.Errorf("FetchChapters() labels = %v, want %v", labels, expected)
+		}
+		if chapters[0].GetVolume() != "1" || chapters[1].GetTitle() != "Side Story" {
+			t.Errorf("FetchChapters() = %+v, %+v, want the volume and title parsed from the names", chapters[0], chapters[1])
+		}
+	}
+}
+
+func TestLocal_FetchChapter(t *testing.T) {
+	dir := localLibrary(t)
+	l := NewLocal(&Grabber{URL: dir})
+
+	chapters, errs := l.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+
+	tests := []struct {
+		chapter  Filterable
+		expected []string
+	}{
+		{chapter: chapters[0], expected: []string{"page 1", "page 2", "page 10"}},
+		{chapter: chapters[1], expected: []string{"archived page 1", "archived page 2"}},
+	}
+
+	for _, tt := range tests {
+		chapter, err := l.FetchChapter(tt.chapter)
+		if err != nil {
+			t.Fatalf("FetchChapter(%s) error = %v", tt.chapter.GetLabel(), err)
+		}
+		if chapter.PagesCount != int64(len(tt.expected)) || chapter.GetId() != tt.chapter.GetId() {
+			t.Errorf("FetchChapter(%s) = %+v, want %d pages", tt.chapter.GetLabel(), chapter, len(tt.expected))
+		}
+
+		var pages []string
+		for _, page := range chapter.Pages {
+			data, err := l.FetchPage(page)
+			if err != nil {
+				t.Fatalf("FetchPage(%s) error = %v", page.URL, err)
+			}
+			pages = append(pages, string(data))
+		}
+		if !reflect.DeepEqual(pages, tt.expected) {
+			t.Errorf("FetchChapter(%s) pages = %v, want %v", tt.chapter.GetLabel(), pages, tt.expected)
+		}
+	}
+
+	if _, err := l.FetchPage(Page{Number: 1, URL: "https://example.com/1.jpg"}); err == nil {
+		t.Error("FetchPage() error = nil for a remote page")
+	}
+
+	// the archive is read by FetchChapter, its pages don't open it again
+	chapter, err := l.FetchChapter(chapters[1])
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+	if err := os.Remove(chapters[1].GetId()); err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range chapter.Pages {
+		if _, err := l.FetchPage(page); err != nil {
+			t.Errorf("FetchPage(%s) error = %v once the archive was read", page.URL, err)
+		}
+	}
+}
+
+func TestIsLocalPath(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: dir, expected: true},
+		{url: "file://" + dir, expected: true},
+		{url: filepath.Join(dir, "missing"), expected: false},
+		{url: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f", expected: false},
+		{url: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		if ok := isLocalPath(tt.url); ok != tt.expected {
+			t.Errorf("isLocalPath(%q) = %v, want %v", tt.url, ok, tt.expected)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package grabber
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.sammcclenaghan.com/mango/http"
@@ -15,29 +19,108 @@ import (
 // Mangadx is a grabber for mangadex.org
 type Mangadx struct {
 	*Grabber
-	title string
-	// rateLimiter rate limiter for the FetchChapter method. This call uses the '/at-home' endpoint which has a rate limit
-	// of 40 calls per minute, if we exceed this limit we get a 429, and the consequent chapters fail. This may eventually
-	// lead to an IP ban.
+	// ApiUrl is the base URL of the MangaDex API
+	ApiUrl string
+	// UploadsUrl is the origin image server used when MangaDex@Home nodes fail
+	UploadsUrl string
+	// ReportUrl is the MangaDex@Home endpoint image downloads are reported to, reports are disabled when empty
+	ReportUrl string
+	title     string
+	manga     *mangadxManga
+	// feedLimit is the number of chapters requested per feed page, feedWindow the furthest offset plus limit the API
+	// serves for a single query
+	feedLimit  int
+	feedWindow int
+	// rateLimiter rate limiter for the FetchChapter and RefreshChapter methods. They use the '/at-home' endpoint which
+	// has a rate limit of 40 calls per minute, if we exceed this limit we get a 429, and the consequent chapters fail.
+	// This may eventually lead to an IP ban.
 	rateLimiter <-chan time.Time
 }
 
+func init() {
+	Register("MangaDex", mangadxUrlRe.MatchString, func(g *Grabber) GrabberInterface {
+		return NewMangadx(g)
+	})
+}
+
+// mangadxUrlRe matches mangadex URLs
+var mangadxUrlRe = regexp.MustCompile(`mangadex\.org`)
+
+// mangadxKindUrlRe matches the kind of page of mangadex URLs
+var mangadxKindUrlRe = regexp.MustCompile(`mangadex\.org/(title|chapter|list|group)/`)
+
+// mangadxApiUrl is the default base URL of the MangaDex API
+const mangadxApiUrl = "https://api.mangadex.org"
+
+// mangadxUploadsUrl is the default origin image server
+const mangadxUploadsUrl = "https://uploads.mangadex.org"
+
+// mangadxOneshotLabel is the label of chapters without a number
+const mangadxOneshotLabel = "Oneshot"
+
+// mangadxCoverLimit is the maximum number of volume covers requested
+const mangadxCoverLimit = 100
+
+// mangadxReportUrl is the default MangaDex@Home report endpoint
+const mangadxReportUrl = "https://api.mangadex.network/report"
+
+// mangadxReportTimeout bounds a report request, reports are best effort and a slow endpoint must not hold them up
+const mangadxReportTimeout = 5 * time.Second
+
+// mangadxFeedLimit is the number of chapters requested per feed page, the most the API allows
+const mangadxFeedLimit = 500
+
+// mangadxFeedWindow is the furthest offset plus limit the API serves for a query
+const mangadxFeedWindow = 10000
+
+// mangadxListBatch is the number of list titles resolved per request
+const mangadxListBatch = 100
+
+// mangadxSearchLimit is the maximum number of results returned by Search
+const mangadxSearchLimit = 10
+
 func NewMangadx(g *Grabber) *Mangadx {
 	// we set the rate limit at 39 calls per minute instead of 40 to make sure the rate limit is under the threshold,
 	// otherwise we occasionally get hit by the rate limiter.
-	return &Mangadx{Grabber: g, rateLimiter: time.Tick(time.Minute / 39)}
+	return &Mangadx{
+		Grabber:     g,
+		ApiUrl:      mangadxApiUrl,
+		UploadsUrl:  mangadxUploadsUrl,
+		ReportUrl:   mangadxReportUrl,
+		feedLimit:   mangadxFeedLimit,
+		feedWindow:  mangadxFeedWindow,
+		rateLimiter: time.Tick(time.Minute / 39),
+	}
 }
 
 // MangadxChapter represents a MangaDx Chapter
 type MangadxChapter struct {
 	Chapter
 	Id string
+	// ExternalUrl is the official release of chapters hosted outside MangaDex
+	ExternalUrl string
+	// Unavailable is set for chapters MangaDex flagged as unavailable
+	Unavailable bool
+}
+
+// IsUnavailable reports whether the chapter pages can't be downloaded from MangaDex
+func (c MangadxChapter) IsUnavailable() bool {
+	return c.Unavailable || c.ExternalUrl != ""
+}
+
+// ExternalLink returns the official release of the chapter, if it's hosted outside MangaDex
+func (c MangadxChapter) ExternalLink() string {
+	return c.ExternalUrl
+}
+
+// GetId returns the MangaDex id of the chapter
+func (c MangadxChapter) GetId() string {
+	return c.Id
 }
 
 // Test checks if the site is MangaDx
 func (m *Mangadx) Test() (bool, error) {
-	re := regexp.MustCompile(`mangadex\.org`)
-	return re.MatchString(m.URL), nil
+	return mangadxUrlRe.MatchString(m.URL), nil
 }
 
 // GetTitle returns the title of the manga
@@ -45,132 +128,877 @@ func (m *Mangadx) FetchTitle() (string, error) {
 	if m.title != "" {
 		return m.title, nil
 	}
+	if kind := m.Classify(); kind != TitleURL {
+		return "", fmt.Errorf("%s is a %s URL, not a title", m.URL, kind)
+	}
 
+	body, err := m.fetchManga()
+	if err != nil {
+		return "", err
+	}
+
+	m.title = m.localizedTitle(body.Data.Attributes.Title, body.Data.Attributes.AltTitles)
+
+	return m.title, nil
+}
+
+// fetchManga fetches the manga json object of the URL, it's requested once and shared by the title, cover and
+// metadata lookups
+func (m *Mangadx) fetchManga() (*mangadxManga, error) {
+	if m.manga != nil {
+		return m.manga, nil
+	}
 	id := getUuid(m.URL)
 
-	rbody, err := http.Get(http.RequestParams{
-		URL:     "https://api.mangadex.org/manga/" + id,
+	rbody, err := m.apiGet(http.RequestParams{
+		URL:     m.ApiUrl + "/manga/" + id + "?includes[]=cover_art&includes[]=author&includes[]=artist",
 		Referer: m.BaseUrl(),
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer rbody.Close()
 
 	// decode json response
-	body := mangadxManga{}
-	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
-		return "", err
+	body := &mangadxManga{}
+	if err = json.NewDecoder(rbody).Decode(body); err != nil {
+		return nil, err
+	}
+
+	// a single title lookup isn't filtered by the API
+	if rating := body.Data.Attributes.ContentRating; !m.Settings.AllowsContentRating(rating) {
+		return nil, &ContentRatingError{Rating: rating, Allowed: m.Settings.ContentRatingFilter()}
+	}
+
+	m.manga = body
+	return body, nil
+}
+
+// FetchMetadata returns the metadata of the manga
+func (m *Mangadx) FetchMetadata() (*SeriesMetadata, error) {
+	body, err := m.fetchManga()
+	if err != nil {
+		return nil, err
+	}
+	attrs := body.Data.Attributes
+
+	meta := &SeriesMetadata{
+		Title:            m.localizedTitle(attrs.Title, attrs.AltTitles),
+		Description:      m.localized(attrs.Description),
+		Authors:          body.Data.Relationships.names("author"),
+		Artists:          body.Data.Relationships.names("artist"),
+		Status:           attrs.Status,
+		Year:             attrs.Year,
+		Demographic:      attrs.PublicationDemographic,
+		OriginalLanguage: attrs.OriginalLanguage,
+		ContentRating:    attrs.ContentRating,
+		Links:            make(map[string]string),
+	}
+
+	for _, tag := range attrs.Tags {
+		name := m.localized(tag.Attributes.Name)
+		if tag.Attributes.Group == "genre" {
+			meta.Genres = append(meta.Genres, name)
+		} else {
+			meta.Tags = append(meta.Tags, name)
+		}
+	}
+
+	for key, value := range attrs.Links {
+		if site, ok := mangadxLinkSites[key]; ok {
+			meta.Links[site.name] = fmt.Sprintf(site.url, value)
+		}
+	}
+
+	return meta, nil
+}
+
+// mangadxLinkSites maps the keys of the manga links to the external site name and the format of its URL, the value
+// of the link is either an id or a full URL
+var mangadxLinkSites = map[string]struct{ name, url string }{
+	"al":    {"AniList", "https://anilist.co/manga/%s"},
+	"ap":    {"Anime-Planet", "https://www.anime-planet.com/manga/%s"},
+	"bw":    {"BookWalker", "https://bookwalker.jp/%s"},
+	"kt":    {"Kitsu", "https://kitsu.app/manga/%s"},
+	"mu":    {"MangaUpdates", "https://www.mangaupdates.com/series/%s"},
+	"mal":   {"MyAnimeList", "https://myanimelist.net/manga/%s"},
+	"nu":    {"NovelUpdates", "https://www.novelupdates.com/series/%s"},
+	"amz":   {"Amazon", "%s"},
+	"ebj":   {"eBookJapan", "%s"},
+	"cdj":   {"CDJapan", "%s"},
+	"raw":   {"Raw", "%s"},
+	"engtl": {"Official English", "%s"},
+}
+
+// localized returns the text in the first requested language it exists in, falling back to english and then to
+// any language
+func (m *Mangadx) localized(texts map[string]string) string {
+	for _, lang := range m.Settings.LanguagePriority() {
+		if text := texts[lang]; text != "" {
+			return text
+		}
+	}
+
+	if text := texts["en"]; text != "" {
+		return text
+	}
+
+	// maps have no order, pick the smallest language code so the result is stable
+	var langs []string
+	for lang := range texts {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if texts[lang] != "" {
+			return texts[lang]
+		}
 	}
 
-	// fetch the title in the requested language
-	if m.Settings.Language != "" {
-		trans := body.Data.Attributes.AltTitles.GetTitleByLang(m.Settings.Language)
+	return ""
+}
 
-		if trans != "" {
-			m.title = trans
-			return m.title, nil
+// localizedTitle returns the title in the first requested language it exists in, falling back to english
+func (m *Mangadx) localizedTitle(title map[string]string, alt altTitles) string {
+	// fetch the title in the requested languages
+	for _, lang := range m.Settings.LanguagePriority() {
+		if trans := alt.GetTitleByLang(lang); trans != "" {
+			return trans
 		}
 	}
 
 	// fallback to english
-	m.title = body.Data.Attributes.Title["en"]
+	if title["en"] != "" {
+		return title["en"]
+	}
 
-	return m.title, nil
+	// then to the romanized and original titles, and to the smallest language code so the title, and the file
+	// names built from it, are the same on every run
+	for _, lang := range []string{"ja-ro", "ja"} {
+		if title[lang] != "" {
+			return title[lang]
+		}
+	}
+	langs := make([]string, 0, len(title))
+	for lang := range title {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if title[lang] != "" {
+			return title[lang]
+		}
+	}
+
+	return ""
+}
+
+// Search searches MangaDex titles by name
+func (m *Mangadx) Search(query string) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Add("title", query)
+	params.Add("limit", fmt.Sprint(mangadxSearchLimit))
+	params.Add("order[relevance]", "desc")
+	m.addContentRatings(params)
+
+	rbody, err := m.apiGet(http.RequestParams{
+		URL: fmt.Sprintf("%s/manga?%s", m.ApiUrl, params.Encode()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rbody.Close()
+
+	// decode json response
+	body := mangadxMangaList{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return m.searchResults(body), nil
+}
+
+// searchResults returns the titles of a manga list response
+func (m *Mangadx) searchResults(body mangadxMangaList) []SearchResult {
+	results := make([]SearchResult, 0, len(body.Data))
+	for _, d := range body.Data {
+		results = append(results, SearchResult{
+			Title:     m.localizedTitle(d.Attributes.Title, d.Attributes.AltTitles),
+			Id:        d.Id,
+			URL:       "https://mangadex.org/title/" + d.Id,
+			Year:      d.Attributes.Year,
+			Status:    d.Attributes.Status,
+			Languages: d.Attributes.AvailableTranslatedLanguages,
+		})
+	}
+
+	return results
+}
+
+// Classify tells whether the URL is a title, a chapter, a custom list or a scanlation group
+func (m *Mangadx) Classify() URLKind {
+	match := mangadxKindUrlRe.FindStringSubmatch(m.URL)
+	if match == nil {
+		return TitleURL
+	}
+
+	switch match[1] {
+	case "chapter":
+		return ChapterURL
+	case "list":
+		return ListURL
+	case "group":
+		return GroupURL
+	}
+	return TitleURL
+}
+
+// ResolveChapter returns the title URL and the chapter of the chapter URL
+func (m *Mangadx) ResolveChapter() (string, Filterable, error) {
+	rbody, err := m.apiGet(http.RequestParams{
+		URL: fmt.Sprintf("%s/chapter/%s?includes[]=scanlation_group", m.ApiUrl, getUuid(m.URL)),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	defer rbody.Close()
+
+	body := struct{ Data mangadxFeedChapter }{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		return "", nil, err
+	}
+
+	for _, rel := range body.Data.Relationships {
+		if rel.Type == "manga" {
+			return "https://mangadex.org/title/" + rel.Id, body.Data.chapter(), nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("chapter %s has no title", getUuid(m.URL))
+}
+
+// FetchList returns the titles of the custom list of the URL, in list order
+func (m *Mangadx) FetchList() (*TitleList, error) {
+	rbody, err := m.apiGet(http.RequestParams{
+		URL: fmt.Sprintf("%s/list/%s", m.ApiUrl, getUuid(m.URL)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rbody.Close()
+
+	body := mangadxList{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, rel := range body.Data.Relationships {
+		if rel.Type == "manga" {
+			ids = append(ids, rel.Id)
+		}
+	}
+
+	// resolve the titles in batches, every content rating is requested so no title of the list goes missing
+	found := make(map[string]SearchResult, len(ids))
+	for start := 0; start < len(ids); start += mangadxListBatch {
+		end := start + mangadxListBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		params := url.Values{}
+		params.Add("limit", fmt.Sprint(mangadxListBatch))
+		for _, id := range ids[start:end] {
+			params.Add("ids[]", id)
+		}
+		for _, rating := range ContentRatings {
+			params.Add("contentRating[]", rating)
+		}
+
+		rbody, err := m.apiGet(http.RequestParams{
+			URL: fmt.Sprintf("%s/manga?%s", m.ApiUrl, params.Encode()),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		mangas := mangadxMangaList{}
+		err = json.NewDecoder(rbody).Decode(&mangas)
+		rbody.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range m.searchResults(mangas) {
+			found[result.Id] = result
+		}
+	}
+
+	list := &TitleList{Name: body.Data.Attributes.Name}
+	for _, id := range ids {
+		result, ok := found[id]
+		if !ok {
+			// titles that couldn't be resolved are still downloaded, they'll tell what's wrong with them
+			result = SearchResult{Title: id, Id: id, URL: "https://mangadex.org/title/" + id}
+		}
+		list.Titles = append(list.Titles, result)
+	}
+
+	return list, nil
 }
 
 // FetchChapters returns the chapters of the manga
 func (m Mangadx) FetchChapters() (chapters Filterables, errs []error) {
-	id := getUuid(m.URL)
+	params := url.Values{}
+	params.Add("order[volume]", "asc")
+	params.Add("order[chapter]", "asc")
+	params.Add("includes[]", "scanlation_group")
+	m.addContentRatings(params)
 
-	baseOffset := 500
-	var fetchChaps func(int)
+	feed, split, err := m.fetchFeed(params, m.Settings.LanguagePriority())
+	if err != nil {
+		return nil, []error{err}
+	}
 
-	fetchChaps = func(offset int) {
-		uri := fmt.Sprintf("https://api.mangadex.org/manga/%s/feed", id)
-		params := url.Values{}
-		params.Add("limit", fmt.Sprint(baseOffset))
-		params.Add("order[volume]", "asc")
-		params.Add("order[chapter]", "asc")
-		params.Add("offset", fmt.Sprint(offset))
-		if m.Settings.Language != "" {
-			params.Add("translatedLanguage[]", m.Settings.Language)
+	for _, c := range feed {
+		chapters = append(chapters, c.chapter())
+	}
+
+	// split queries come back in language or upload order
+	if split {
+		SortChapters(chapters)
+	}
+
+	// tell which languages the manga is available in rather than returning nothing
+	if len(chapters) == 0 && len(m.Settings.LanguagePriority()) > 0 {
+		if manga, err := m.fetchManga(); err == nil {
+			errs = append(errs, &NoChaptersError{
+				Languages: m.Settings.LanguagePriority(),
+				Available: manga.Data.Attributes.AvailableTranslatedLanguages,
+			})
+		}
+	}
+
+	return
+}
+
+// fetchFeed fetches every chapter of the feed in the given languages. The API serves at most feedWindow results per
+// query, longer feeds are split by language and then by upload date, in which case split is set.
+func (m Mangadx) fetchFeed(params url.Values, languages []string) (feed []mangadxFeedChapter, split bool, err error) {
+	query := cloneValues(params)
+	for _, lang := range languages {
+		query.Add("translatedLanguage[]", lang)
+	}
+
+	feed, total, err := m.fetchFeedPages(m.feedUrl(), query)
+	if err != nil || total <= len(feed) {
+		return feed, false, err
+	}
+
+	if len(languages) == 1 {
+		feed, err = m.fetchFeedWindows(query, total)
+		return feed, true, err
+	}
+
+	// query each language on its own, every language the title is translated to when none was requested
+	if len(languages) == 0 {
+		manga, err := m.fetchManga()
+		if err != nil {
+			return nil, true, err
 		}
-		uri = fmt.Sprintf("%s?%s", uri, params.Encode())
+		languages = manga.Data.Attributes.AvailableTranslatedLanguages
+	}
 
-		rbody, err := http.Get(http.RequestParams{URL: uri})
+	feed = nil
+	for _, lang := range languages {
+		chapters, _, err := m.fetchFeed(params, []string{lang})
 		if err != nil {
-			errs = append(errs, err)
-			return
+			return nil, true, err
 		}
-		defer rbody.Close()
-		// parse json body
+		feed = append(feed, chapters...)
+	}
+
+	if len(feed) < total {
+		return nil, true, &IncompleteFeedError{Fetched: len(feed), Total: total}
+	}
+
+	return feed, true, nil
+}
+
+// fetchFeedWindows fetches a feed larger than feedWindow by upload date, each query starting where the previous one
+// ended. Windows overlap by a second so chapters uploaded together aren't lost, the overlap is dropped by id.
+func (m Mangadx) fetchFeedWindows(params url.Values, total int) (feed []mangadxFeedChapter, err error) {
+	query := cloneValues(params)
+	query.Del("order[volume]")
+	query.Del("order[chapter]")
+	query.Set("order[createdAt]", "asc")
+
+	seen := make(map[string]bool)
+	for {
+		chapters, remaining, err := m.fetchFeedPages(m.feedUrl(), query)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, c := range chapters {
+			if !seen[c.Id] {
+				seen[c.Id] = true
+				feed = append(feed, c)
+				added++
+			}
+		}
+
+		if remaining <= len(chapters) {
+			return feed, nil
+		}
+
+		// a window full of chapters uploaded within the same second can't be moved past
+		since, err := time.Parse(time.RFC3339, chapters[len(chapters)-1].Attributes.CreatedAt)
+		if err != nil || added == 0 {
+			return nil, &IncompleteFeedError{Fetched: len(feed), Total: total}
+		}
+		query.Set("createdAtSince", since.UTC().Add(-time.Second).Format("2006-01-02T15:04:05"))
+	}
+}
+
+// feedUrl returns the URL of the chapter feed of the manga
+func (m Mangadx) feedUrl() string {
+	return fmt.Sprintf("%s/manga/%s/feed", m.ApiUrl, getUuid(m.URL))
+}
+
+// fetchFeedPages fetches a feed query page by page, up to feedWindow chapters, and returns them along with the total
+// number of chapters matching the query
+func (m Mangadx) fetchFeedPages(uri string, params url.Values) (feed []mangadxFeedChapter, total int, err error) {
+	query := cloneValues(params)
+	query.Set("limit", fmt.Sprint(m.feedLimit))
+
+	for offset := 0; offset+m.feedLimit <= m.feedWindow; offset += m.feedLimit {
+		query.Set("offset", fmt.Sprint(offset))
+
+		rbody, err := m.apiGet(http.RequestParams{
+			URL: uri + "?" + query.Encode(),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
 		body := mangadxFeed{}
-		if err = json.NewDecoder(rbody).Decode(&body); err != nil {
-			errs = append(errs, err)
-			return
-		}
-
-		for _, c := range body.Data {
-			num, _ := strconv.ParseFloat(c.Attributes.Chapter, 64)
-			chapters = append(chapters, &MangadxChapter{
-				Chapter{
-					Number:     num,
-					Title:      c.Attributes.Title,
-					Language:   c.Attributes.TranslatedLanguage,
-					PagesCount: c.Attributes.Pages,
-				},
-				c.Id,
-			})
+		err = json.NewDecoder(rbody).Decode(&body)
+		rbody.Close()
+		if err != nil {
+			return nil, 0, err
 		}
 
-		if len(body.Data) > 0 {
-			fetchChaps(offset + baseOffset)
+		feed = append(feed, body.Data...)
+		total = body.Total
+		if len(body.Data) == 0 || offset+len(body.Data) >= total {
+			break
 		}
 	}
-	// initial call
-	fetchChaps(0)
 
-	return
+	return feed, total, nil
+}
+
+// cloneValues returns a copy of the query parameters
+func cloneValues(v url.Values) url.Values {
+	clone := url.Values{}
+	for key, values := range v {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+// FetchFollowedChapters returns the chapters uploaded since the given time to the series the logged in user
+// follows, grouped by series
+func (m Mangadx) FetchFollowedChapters(since time.Time) ([]FollowedSeries, error) {
+	if m.Settings.Auth == nil {
+		return nil, ErrLoginRequired
+	}
+
+	params := url.Values{}
+	params.Add("order[createdAt]", "asc")
+	params.Add("createdAtSince", since.UTC().Format("2006-01-02T15:04:05"))
+	params.Add("includes[]", "scanlation_group")
+	params.Add("includes[]", "manga")
+	m.addLanguages(params)
+	m.addContentRatings(params)
+
+	feed, total, err := m.fetchFeedPages(m.ApiUrl+"/user/follows/manga/feed", params)
+	if err != nil {
+		return nil, err
+	}
+	if total > len(feed) {
+		return nil, &IncompleteFeedError{Fetched: len(feed), Total: total}
+	}
+
+	var followed []FollowedSeries
+	index := make(map[string]int)
+	for _, c := range feed {
+		for _, rel := range c.Relationships {
+			if rel.Type != "manga" {
+				continue
+			}
+
+			i, ok := index[rel.Id]
+			if !ok {
+				i = len(followed)
+				index[rel.Id] = i
+				followed = append(followed, FollowedSeries{
+					Title: m.localizedTitle(rel.Attributes.Title, rel.Attributes.AltTitles),
+					URL:   "https://mangadex.org/title/" + rel.Id,
+				})
+			}
+			followed[i].Chapters = append(followed[i].Chapters, c.chapter())
+		}
+	}
+
+	for _, series := range followed {
+		SortChapters(series.Chapters)
+	}
+
+	return followed, nil
+}
+
+// ReadChapters returns the chapters the logged in user read among the given ones
+func (m Mangadx) ReadChapters(chapters Filterables) (Filterables, error) {
+	if m.Settings.Auth == nil {
+		return nil, ErrLoginRequired
+	}
+
+	rbody, err := m.apiGet(http.RequestParams{
+		URL: fmt.Sprintf("%s/manga/%s/read", m.ApiUrl, getUuid(m.URL)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rbody.Close()
+
+	body := struct{ Data []string }{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	markers := make(map[string]bool, len(body.Data))
+	for _, id := range body.Data {
+		markers[id] = true
+	}
+
+	var read Filterables
+	for _, chapter := range chapters {
+		if markers[chapter.GetId()] {
+			read = append(read, chapter)
+		}
+	}
+
+	return read, nil
+}
+
+// MarkRead marks the chapters read on the account of the logged in user
+func (m Mangadx) MarkRead(chapters Filterables) error {
+	if m.Settings.Auth == nil {
+		return ErrLoginRequired
+	}
+
+	payload := mangadxReadMarkers{ChapterIdsRead: []string{}, ChapterIdsUnread: []string{}}
+	for _, chapter := range chapters {
+		if id := chapter.GetId(); id != "" {
+			payload.ChapterIdsRead = append(payload.ChapterIdsRead, id)
+		}
+	}
+	if len(payload.ChapterIdsRead) == 0 {
+		return nil
+	}
+
+	rbody, err := m.apiPost(http.RequestParams{
+		URL: fmt.Sprintf("%s/manga/%s/read", m.ApiUrl, getUuid(m.URL)),
+	}, payload)
+	if err != nil {
+		return err
+	}
+	rbody.Close()
+
+	return nil
+}
+
+// apiGet performs a GET request to the API, authenticated when the settings hold a session
+func (m Mangadx) apiGet(params http.RequestParams) (io.ReadCloser, error) {
+	params, err := m.authorize(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.Get(params)
+}
+
+// apiPost posts a JSON payload to the API, authenticated when the settings hold a session
+func (m Mangadx) apiPost(params http.RequestParams, payload interface{}) (io.ReadCloser, error) {
+	params, err := m.authorize(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.Post(params, "application/json", bytes.NewReader(body))
+}
+
+// authorize adds the session token to the request parameters, if the settings hold a session
+func (m Mangadx) authorize(params http.RequestParams) (http.RequestParams, error) {
+	if m.Settings.Auth == nil {
+		return params, nil
+	}
+
+	token, err := m.Settings.Auth.Token()
+	if err != nil {
+		return params, err
+	}
+
+	// copy the headers so the caller's map is left untouched
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	for key, value := range params.Headers {
+		headers[key] = value
+	}
+	params.Headers = headers
+
+	return params, nil
+}
+
+// addLanguages adds the requested translation languages to the query parameters
+func (m Mangadx) addLanguages(params url.Values) {
+	for _, lang := range m.Settings.LanguagePriority() {
+		params.Add("translatedLanguage[]", lang)
+	}
+}
+
+// addContentRatings adds the content rating filter to the query parameters, so it's never left to the API defaults
+func (m Mangadx) addContentRatings(params url.Values) {
+	for _, rating := range m.Settings.ContentRatingFilter() {
+		params.Add("contentRating[]", rating)
+	}
 }
 
 // FetchChapter fetches a chapter and its pages
 func (m Mangadx) FetchChapter(f Filterable) (*Chapter, error) {
-	<-m.rateLimiter
 	chap := f.(*MangadxChapter)
+
+	body, err := m.atHome(chap.Id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	chapter := &Chapter{
+		Title:    chapterTitle(f),
+		Number:   f.GetNumber(),
+		Label:    f.GetLabel(),
+		Volume:   chap.Volume,
+		Language: chap.Language,
+		Groups:   chap.Groups,
+		id:       chap.Id,
+	}
+	m.setPages(chapter, body.BaseUrl, body)
+
+	return chapter, nil
+}
+
+// chapterTitle returns the title of a downloaded chapter
+func chapterTitle(f Filterable) string {
+	if IsNumbered(f) {
+		return fmt.Sprintf("Chapter %04d %s", int64(f.GetNumber()), f.GetTitle())
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", ChapterName(f), f.GetTitle()))
+}
+
+// RefreshChapter returns the chapter with its pages served by another image source. The first attempt asks for a
+// new MangaDex@Home node, the second for a node on port 443 and the last one uses the origin uploads server.
+func (m Mangadx) RefreshChapter(chapter *Chapter, attempt int) (*Chapter, error) {
+	if chapter.id == "" {
+		return nil, fmt.Errorf("chapter %.1f has no MangaDex id: %w", chapter.Number, ErrNoImageSources)
+	}
+	if attempt > 2 {
+		return nil, ErrNoImageSources
+	}
+
+	body, err := m.atHome(chapter.id, attempt == 1)
+	if err != nil {
+		// the uploads server holds the files at the same paths, so it can be reached without the at-home API
+		if attempt == 2 {
+			if refreshed := m.uploadsChapter(chapter); refreshed != nil {
+				return refreshed, nil
+			}
+		}
+		return nil, err
+	}
+
+	baseUrl := body.BaseUrl
+	if attempt == 2 {
+		baseUrl = m.UploadsUrl
+	}
+
+	refreshed := *chapter
+	refreshed.Pages = nil
+	m.setPages(&refreshed, baseUrl, body)
+
+	return &refreshed, nil
+}
+
+// uploadsChapter returns the chapter with its pages moved to the uploads server, or nil if the page URLs have no
+// data path
+func (m Mangadx) uploadsChapter(chapter *Chapter) *Chapter {
+	refreshed := *chapter
+	refreshed.Pages = nil
+	for _, page := range chapter.Pages {
+		u, err := url.Parse(page.URL)
+		if err != nil {
+			return nil
+		}
+		idx := strings.Index(u.Path, "/data")
+		if idx < 0 {
+			return nil
+		}
+		page.URL = m.UploadsUrl + u.Path[idx:]
+		refreshed.Pages = append(refreshed.Pages, page)
+	}
+
+	return &refreshed
+}
+
+// atHome asks the at-home API for the server and files of a chapter
+func (m Mangadx) atHome(id string, forcePort443 bool) (*mangadxPagesFeed, error) {
+	<-m.rateLimiter
+
+	uri := m.ApiUrl + "/at-home/server/" + id
+	if forcePort443 {
+		uri += "?forcePort443=true"
+	}
+
 	// download json
-	rbody, err := http.Get(http.RequestParams{
-		URL: "https://api.mangadex.org/at-home/server/" + chap.Id,
-	})
+	rbody, err := m.apiGet(http.RequestParams{URL: uri})
 	if err != nil {
 		return nil, err
 	}
 	defer rbody.Close()
 	// parse json body
-	body := mangadxPagesFeed{}
-	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+	body := &mangadxPagesFeed{}
+	if err = json.NewDecoder(rbody).Decode(body); err != nil {
 		return nil, err
 	}
 
-	pcount := len(body.Chapter.Data)
+	return body, nil
+}
 
-	chapter := &Chapter{
-		Title:      fmt.Sprintf("Chapter %04d %s", int64(f.GetNumber()), chap.Title),
-		Number:     f.GetNumber(),
-		PagesCount: int64(pcount),
-		Language:   chap.Language,
+// setPages fills the chapter pages with the files of the at-home response served from baseUrl
+func (m Mangadx) setPages(chapter *Chapter, baseUrl string, body *mangadxPagesFeed) {
+	// pick the compressed images when data saver is enabled
+	files, dataPath, quality := body.Chapter.Data, "/data", QualityOriginal
+	if m.Settings.DataSaver && len(body.Chapter.DataSaver) > 0 {
+		files, dataPath, quality = body.Chapter.DataSaver, "/data-saver", QualityDataSaver
 	}
 
+	chapter.PagesCount = int64(len(files))
+	chapter.Quality = quality
+
 	// create pages
-	for i, p := range body.Chapter.Data {
+	for i, p := range files {
 		num := i + 1
 		chapter.Pages = append(chapter.Pages, Page{
 			Number: int64(num),
-			URL:    body.BaseUrl + path.Join("/data", body.Chapter.Hash, p),
+			URL:    baseUrl + path.Join(dataPath, body.Chapter.Hash, p),
 		})
 	}
+}
 
-	return chapter, nil
+// Report sends the outcome of an image download to the MangaDex@Home network. Images served by mangadex.org itself
+// are not part of the network and are not reported.
+func (m *Mangadx) Report(r ImageReport) error {
+	if m.ReportUrl == "" {
+		return nil
+	}
+
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	if u.Hostname() == "mangadex.org" || strings.HasSuffix(u.Hostname(), ".mangadex.org") {
+		return nil
+	}
+
+	payload, err := json.Marshal(mangadxReport{
+		Url:      r.URL,
+		Success:  r.Success,
+		Bytes:    r.Bytes,
+		Duration: r.Duration.Milliseconds(),
+		Cached:   r.Cached,
+	})
+	if err != nil {
+		return err
+	}
+
+	rbody, err := http.Post(http.RequestParams{URL: m.ReportUrl, Timeout: mangadxReportTimeout}, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	rbody.Close()
+
+	return nil
+}
+
+// FetchCovers returns the main cover of the manga followed by the cover of each of its volumes
+func (m *Mangadx) FetchCovers() ([]Cover, error) {
+	id := getUuid(m.URL)
+
+	manga, err := m.fetchManga()
+	if err != nil {
+		return nil, err
+	}
+
+	var covers []Cover
+	for _, rel := range manga.Data.Relationships {
+		if rel.Type == "cover_art" && rel.Attributes.FileName != "" {
+			covers = append(covers, Cover{URL: m.coverUrl(id, rel.Attributes.FileName)})
+		}
+	}
+
+	params := url.Values{}
+	params.Add("manga[]", id)
+	params.Add("limit", fmt.Sprint(mangadxCoverLimit))
+	params.Add("order[volume]", "asc")
+
+	rbody, err := m.apiGet(http.RequestParams{
+		URL:     fmt.Sprintf("%s/cover?%s", m.ApiUrl, params.Encode()),
+		Referer: m.BaseUrl(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rbody.Close()
+
+	body := mangadxCoverList{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	// a volume may have a cover per locale, keep the first one
+	seen := make(map[string]bool)
+	for _, c := range body.Data {
+		volume := c.Attributes.Volume
+		if volume == "" || seen[volume] {
+			continue
+		}
+		seen[volume] = true
+		covers = append(covers, Cover{Volume: volume, URL: m.coverUrl(id, c.Attributes.FileName)})
+	}
+
+	return covers, nil
+}
+
+// coverUrl returns the URL of a cover image of the manga
+func (m *Mangadx) coverUrl(mangaId, fileName string) string {
+	return fmt.Sprintf("%s/covers/%s/%s", m.UploadsUrl, mangaId, fileName)
 }
 
 // getUuid extracts the UUID from a MangaDx URL
@@ -183,9 +1011,41 @@ func getUuid(urlStr string) string {
 type mangadxManga struct {
 	Id   string
 	Data struct {
+		Attributes    mangadxMangaAttributes
+		Relationships mangadxRelationships
+	}
+}
+
+// mangadxMangaAttributes represents the attributes of the Manga json object
+type mangadxMangaAttributes struct {
+	Title                        map[string]string
+	AltTitles                    altTitles
+	AvailableTranslatedLanguages []string
+	Description                  map[string]string
+	Status                       string
+	Year                         int
+	PublicationDemographic       string
+	OriginalLanguage             string
+	ContentRating                string
+	Links                        map[string]string
+	Tags                         []struct {
 		Attributes struct {
-			Title     map[string]string
-			AltTitles altTitles
+			Name  map[string]string
+			Group string
+		}
+	}
+}
+
+// mangadxMangaList represents the json object returned by the manga search endpoint
+type mangadxMangaList struct {
+	Data []struct {
+		Id         string
+		Attributes struct {
+			Title                        map[string]string
+			AltTitles                    altTitles
+			Year                         int
+			Status                       string
+			AvailableTranslatedLanguages []string
 		}
 	}
 }
@@ -206,18 +1066,114 @@ func (a altTitles) GetTitleByLang(lang string) string {
 
 // mangadxFeed represents the json object returned by the feed endpoint
 type mangadxFeed struct {
+	Data  []mangadxFeedChapter
+	Total int
+}
+
+// mangadxFeedChapter represents a chapter of a MangaDex feed
+type mangadxFeedChapter struct {
+	Id         string
+	Attributes struct {
+		Volume             string
+		Chapter            string
+		Title              string
+		TranslatedLanguage string
+		Pages              int64
+		ExternalUrl        string
+		IsUnavailable      bool
+		CreatedAt          string
+	}
+	Relationships mangadxRelationships
+}
+
+// chapter returns the chapter of the feed entry
+func (c mangadxFeedChapter) chapter() *MangadxChapter {
+	// non-numeric chapters keep their label, chapters without one are oneshots
+	label := strings.TrimSpace(c.Attributes.Chapter)
+	if label == "" {
+		label = mangadxOneshotLabel
+	}
+	num, _ := strconv.ParseFloat(label, 64)
+
+	return &MangadxChapter{
+		Chapter{
+			Number:     num,
+			Label:      label,
+			Volume:     c.Attributes.Volume,
+			Title:      c.Attributes.Title,
+			Language:   c.Attributes.TranslatedLanguage,
+			Groups:     c.Relationships.names("scanlation_group"),
+			PagesCount: c.Attributes.Pages,
+		},
+		c.Id,
+		c.Attributes.ExternalUrl,
+		c.Attributes.IsUnavailable,
+	}
+}
+
+// mangadxRelationships represents the relationships of a MangaDex entity
+type mangadxRelationships []struct {
+	Id         string
+	Type       string
+	Attributes struct {
+		Name      string
+		FileName  string
+		Title     map[string]string
+		AltTitles altTitles
+	}
+}
+
+// names returns the names of the related entities of the given type, falling back to their ids when the
+// relationship was not expanded
+func (r mangadxRelationships) names(typ string) (names []string) {
+	for _, rel := range r {
+		if rel.Type != typ {
+			continue
+		}
+		if rel.Attributes.Name != "" {
+			names = append(names, rel.Attributes.Name)
+		} else {
+			names = append(names, rel.Id)
+		}
+	}
+	return
+}
+
+// mangadxList represents the custom list json object
+type mangadxList struct {
+	Data struct {
+		Attributes struct {
+			Name string
+		}
+		Relationships mangadxRelationships
+	}
+}
+
+// mangadxReadMarkers represents the read markers update json object
+type mangadxReadMarkers struct {
+	ChapterIdsRead   []string `json:"chapterIdsRead"`
+	ChapterIdsUnread []string `json:"chapterIdsUnread"`
+}
+
+// mangadxCoverList represents the cover list json object
+type mangadxCoverList struct {
 	Data []struct {
-		Id         string
 		Attributes struct {
-			Volume             string
-			Chapter            string
-			Title              string
-			TranslatedLanguage string
-			Pages              int64
+			Volume   string
+			FileName string
 		}
 	}
 }
 
+// mangadxReport represents the json object sent to the MangaDex@Home report endpoint
+type mangadxReport struct {
+	Url      string `json:"url"`
+	Success  bool   `json:"success"`
+	Bytes    int    `json:"bytes"`
+	Duration int64  `json:"duration"`
+	Cached   bool   `json:"cached"`
+}
+
 // mangadxPagesFeed represents the json object returned by the pages endpoint
 type mangadxPagesFeed struct {
 	BaseUrl string
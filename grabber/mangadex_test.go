@@ -2,9 +2,16 @@ package grabber
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMangadex_Test(t *testing.T) {
@@ -52,15 +59,10 @@ func TestMangadex_FetchTitle(t *testing.T) {
 	mockManga := mangadxManga{
 		Id: "test-id",
 		Data: struct {
-			Attributes struct {
-				Title     map[string]string
-				AltTitles altTitles
-			}
+			Attributes    mangadxMangaAttributes
+			Relationships mangadxRelationships
 		}{
-			Attributes: struct {
-				Title     map[string]string
-				AltTitles altTitles
-			}{
+			Attributes: mangadxMangaAttributes{
 				Title: map[string]string{
 					"en": "Test Manga",
 					"ja": "テストマンガ",
@@ -193,6 +195,32 @@ func TestAltTitles_GetTitleByLang(t *testing.T) {
 	}
 }
 
+func TestMangadex_LocalizedTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    map[string]string
+		expected string
+	}{
+		{name: "english", title: map[string]string{"ko": "Korean", "en": "English", "ja-ro": "Romaji"}, expected: "English"},
+		{name: "romanized before original", title: map[string]string{"ja": "日本語", "ja-ro": "Romaji", "ko": "Korean"}, expected: "Romaji"},
+		{name: "original", title: map[string]string{"zh": "Chinese", "ja": "日本語"}, expected: "日本語"},
+		{name: "smallest language code", title: map[string]string{"zh": "Chinese", "ko": "Korean", "fr": "French"}, expected: "French"},
+		{name: "no title", title: map[string]string{}, expected: ""},
+	}
+
+	m := NewMangadx(&Grabber{Settings: Settings{Language: "de"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// maps are iterated in random order, ask a few times so an unstable pick shows
+			for i := 0; i < 20; i++ {
+				if result := m.localizedTitle(tt.title, nil); result != tt.expected {
+					t.Fatalf("localizedTitle() = %q, want %q", result, tt.expected)
+				}
+			}
+		})
+	}
+}
+
 func TestMangadxChapter_Filterable(t *testing.T) {
 	chapter := &MangadxChapter{
 		Chapter: Chapter{
@@ -234,3 +262,924 @@ func TestNewMangadx(t *testing.T) {
 		t.Error("NewMangadx() did not initialize rate limiter")
 	}
 }
+
+func TestMangadex_Search(t *testing.T) {
+	var gotQuery string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manga" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotQuery = r.URL.Query().Get("title")
+		if got := strings.Join(r.URL.Query()["contentRating[]"], ","); got != "safe,suggestive,erotica" {
+			t.Errorf("contentRating[] = %v, want the default content ratings", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"data": [
+				{
+					"id": "a1c7c817-4e59-43b7-9365-09675a149a6f",
+					"attributes": {
+						"title": {"en": "One Piece"},
+						"altTitles": [{"es": "Una Pieza"}],
+						"year": 1997,
+						"status": "ongoing",
+						"availableTranslatedLanguages": ["en", "es"]
+					}
+				},
+				{
+					"id": "b2d8f928-5f6a-44c8-a75b-09765b249b7f",
+					"attributes": {
+						"title": {"ja-ro": "One Piece Party"},
+						"altTitles": [],
+						"year": null,
+						"status": "completed",
+						"availableTranslatedLanguages": []
+					}
+				}
+			]
+		}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{Settings: Settings{Language: "es"}})
+	m.ApiUrl = ts.URL
+
+	results, err := m.Search("one piece")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if gotQuery != "one piece" {
+		t.Errorf("Search() sent title = %q, want %q", gotQuery, "one piece")
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results))
+	}
+
+	first := results[0]
+	if first.Title != "Una Pieza" {
+		t.Errorf("Search() title = %v, want localized title %v", first.Title, "Una Pieza")
+	}
+	if first.Id != "a1c7c817-4e59-43b7-9365-09675a149a6f" {
+		t.Errorf("Search() id = %v", first.Id)
+	}
+	if first.URL != "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f" {
+		t.Errorf("Search() url = %v", first.URL)
+	}
+	if first.Year != 1997 || first.Status != "ongoing" {
+		t.Errorf("Search() year/status = %v/%v, want 1997/ongoing", first.Year, first.Status)
+	}
+	if len(first.Languages) != 2 {
+		t.Errorf("Search() languages = %v, want [en es]", first.Languages)
+	}
+
+	// titles without an english version fall back to whatever title is available
+	if results[1].Title != "One Piece Party" {
+		t.Errorf("Search() fallback title = %v, want %v", results[1].Title, "One Piece Party")
+	}
+	if results[1].Year != 0 {
+		t.Errorf("Search() year for null = %v, want 0", results[1].Year)
+	}
+}
+
+func TestMangadex_SearchError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{})
+	m.ApiUrl = ts.URL
+
+	if _, err := m.Search("anything"); err == nil {
+		t.Error("Search() expected error for failing API, but got none")
+	}
+}
+
+func TestMangadex_FetchChapters(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("includes[]") != "scanlation_group" {
+			t.Errorf("feed request does not include scanlation groups: %s", r.URL.RawQuery)
+		}
+		if len(r.URL.Query()["contentRating[]"]) == 0 {
+			t.Errorf("feed request does not filter content ratings: %s", r.URL.RawQuery)
+		}
+		if r.URL.Query().Get("offset") != "0" {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		w.Write([]byte(`{
+			"data": [
+				{"id": "ch-1", "attributes": {"volume": "1", "chapter": "1", "title": "One", "translatedLanguage": "en", "pages": 10},
+				 "relationships": [
+					{"id": "group-1", "type": "scanlation_group", "attributes": {"name": "Alpha Scans"}},
+					{"id": "user-1", "type": "user"},
+					{"id": "group-2", "type": "scanlation_group"}
+				 ]},
+				{"id": "ch-2", "attributes": {"volume": null, "chapter": "2", "title": "Two", "translatedLanguage": "en", "pages": 12}},
+				{"id": "ch-3", "attributes": {"chapter": "3", "translatedLanguage": "en", "pages": 0, "externalUrl": "https://official.example.com/3"}},
+				{"id": "ch-4", "attributes": {"chapter": "4", "translatedLanguage": "en", "pages": 0, "isUnavailable": true}},
+				{"id": "ch-5", "attributes": {"chapter": null, "title": "Pilot", "translatedLanguage": "en", "pages": 20}},
+				{"id": "ch-6", "attributes": {"chapter": "Extra", "volume": "1", "translatedLanguage": "en", "pages": 4}}
+			]
+		}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{URL: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f"})
+	m.ApiUrl = ts.URL
+
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+
+	if len(chapters) != 6 {
+		t.Fatalf("FetchChapters() returned %d chapters, want 6", len(chapters))
+	}
+
+	if chapters[0].GetVolume() != "1" {
+		t.Errorf("GetVolume() = %q, want %q", chapters[0].GetVolume(), "1")
+	}
+
+	if chapters[1].GetVolume() != "" {
+		t.Errorf("GetVolume() = %q, want empty volume", chapters[1].GetVolume())
+	}
+
+	if groups := chapters[0].GetGroups(); len(groups) != 2 || groups[0] != "Alpha Scans" || groups[1] != "group-2" {
+		t.Errorf("GetGroups() = %v, want [Alpha Scans group-2]", groups)
+	}
+
+	if chapters[0].(*MangadxChapter).Id != "ch-1" {
+		t.Errorf("Id = %q, want %q", chapters[0].(*MangadxChapter).Id, "ch-1")
+	}
+
+	if !IsDownloadable(chapters[0]) {
+		t.Error("IsDownloadable() = false for a chapter hosted on MangaDex")
+	}
+
+	external := chapters[2].(*MangadxChapter)
+	if IsDownloadable(external) || external.ExternalLink() != "https://official.example.com/3" {
+		t.Errorf("external chapter = %+v, want it undownloadable with its official link", external)
+	}
+
+	if IsDownloadable(chapters[3]) {
+		t.Error("IsDownloadable() = true for a chapter flagged unavailable")
+	}
+
+	if label := chapters[0].GetLabel(); label != "1" {
+		t.Errorf("GetLabel() = %q, want %q", label, "1")
+	}
+
+	if key := ChapterKey(chapters[4]); chapters[4].GetLabel() != "Oneshot" || key != "oneshot" {
+		t.Errorf("chapter without number has label %q and key %q, want a oneshot", chapters[4].GetLabel(), key)
+	}
+
+	if key := ChapterKey(chapters[5]); key != "extra (vol 1)" {
+		t.Errorf("ChapterKey() = %q, want %q", key, "extra (vol 1)")
+	}
+}
+
+func TestMangadex_FetchChaptersLanguages(t *testing.T) {
+	var gotLangs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/feed") {
+			gotLangs = r.URL.Query()["translatedLanguage[]"]
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"attributes": {"title": {"en": "Test"}, "availableTranslatedLanguages": ["fr", "pt-br"]}}}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{
+		URL:      "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+		Settings: Settings{Language: "es", Languages: []string{"es", "en"}},
+	})
+	m.ApiUrl = ts.URL
+
+	chapters, errs := m.FetchChapters()
+	if len(chapters) != 0 {
+		t.Fatalf("FetchChapters() returned %d chapters, want 0", len(chapters))
+	}
+
+	if !reflect.DeepEqual(gotLangs, []string{"es", "en"}) {
+		t.Errorf("feed requested languages %v, want [es en]", gotLangs)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("FetchChapters() errors = %v, want a single error", errs)
+	}
+
+	var noChapters *NoChaptersError
+	if !errors.As(errs[0], &noChapters) {
+		t.Fatalf("FetchChapters() error = %T, want *NoChaptersError", errs[0])
+	}
+
+	if !strings.Contains(errs[0].Error(), "fr, pt-br") {
+		t.Errorf("NoChaptersError = %v, want it to list the available languages", errs[0])
+	}
+}
+
+// feedServer serves a MangaDex feed of the given chapters, refusing queries past window like the API does
+func feedServer(t *testing.T, chapters []mangadxFeedChapter, window int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		if !strings.HasSuffix(r.URL.Path, "/feed") {
+			w.Write([]byte(`{"data": {"attributes": {"title": {"en": "Test"}, "availableTranslatedLanguages": ["en", "es"]}}}`))
+			return
+		}
+
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		if offset+limit > window {
+			t.Errorf("feed requested past the window: %s", r.URL.RawQuery)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var matching []mangadxFeedChapter
+		for _, c := range chapters {
+			if langs := q["translatedLanguage[]"]; len(langs) > 0 && langs[0] != c.Attributes.TranslatedLanguage {
+				continue
+			}
+			if since := q.Get("createdAtSince"); since != "" && c.Attributes.CreatedAt[:19] < since {
+				continue
+			}
+			matching = append(matching, c)
+		}
+		if q.Get("order[createdAt]") == "asc" {
+			sort.SliceStable(matching, func(i, j int) bool {
+				return matching[i].Attributes.CreatedAt < matching[j].Attributes.CreatedAt
+			})
+		}
+
+		page := mangadxFeed{Data: []mangadxFeedChapter{}, Total: len(matching)}
+		for i := offset; i < offset+limit && i < len(matching); i++ {
+			page.Data = append(page.Data, matching[i])
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+}
+
+// feedChapter returns a feed chapter numbered n in the given language
+func feedChapter(n int, lang string, uploaded time.Time) mangadxFeedChapter {
+	c := mangadxFeedChapter{Id: fmt.Sprintf("%s-%d", lang, n)}
+	c.Attributes.Chapter = strconv.Itoa(n)
+	c.Attributes.TranslatedLanguage = lang
+	c.Attributes.CreatedAt = uploaded.Format(time.RFC3339)
+	return c
+}
+
+func TestMangadex_FetchChaptersPagination(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var multilingual, long, sameSecond []mangadxFeedChapter
+	for n := 1; n <= 5; n++ {
+		multilingual = append(multilingual, feedChapter(n, "en", start), feedChapter(n, "es", start))
+	}
+	for n := 1; n <= 11; n++ {
+		// chapters 4 and 5 are uploaded within the same second
+		uploaded := start.Add(time.Duration(n) * time.Minute)
+		if n == 5 {
+			uploaded = uploaded.Add(-time.Minute)
+		}
+		long = append(long, feedChapter(n, "en", uploaded))
+		sameSecond = append(sameSecond, feedChapter(n, "en", start))
+	}
+
+	tests := []struct {
+		name      string
+		chapters  []mangadxFeedChapter
+		languages []string
+		expected  int
+	}{
+		{name: "split by language", chapters: multilingual, expected: 10},
+		{name: "split by upload date", chapters: long, languages: []string{"en"}, expected: 11},
+		{name: "incomplete", chapters: sameSecond, languages: []string{"en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := feedServer(t, tt.chapters, 6)
+			defer ts.Close()
+
+			m := NewMangadx(&Grabber{
+				URL:      "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+				Settings: Settings{Languages: tt.languages},
+			})
+			m.ApiUrl = ts.URL
+			m.feedLimit = 2
+			m.feedWindow = 6
+
+			chapters, errs := m.FetchChapters()
+			if tt.expected == 0 {
+				var incomplete *IncompleteFeedError
+				if len(errs) != 1 || !errors.As(errs[0], &incomplete) || chapters != nil {
+					t.Fatalf("FetchChapters() = %d chapters, %v, want an IncompleteFeedError", len(chapters), errs)
+				}
+				return
+			}
+
+			if len(errs) > 0 {
+				t.Fatalf("FetchChapters() errors = %v", errs)
+			}
+			if len(chapters) != tt.expected {
+				t.Fatalf("FetchChapters() returned %d chapters, want %d", len(chapters), tt.expected)
+			}
+			for i := 1; i < len(chapters); i++ {
+				if ChapterLess(chapters[i], chapters[i-1]) {
+					t.Errorf("FetchChapters() chapters out of order: %v after %v", chapters[i].GetLabel(), chapters[i-1].GetLabel())
+				}
+			}
+		})
+	}
+}
+
+func TestMangadex_FetchChapterDataSaver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"baseUrl": "https://node.example.org",
+			"chapter": {"hash": "abc", "data": ["1.png", "2.png"], "dataSaver": ["1.jpg", "2.jpg"]}
+		}`))
+	}))
+	defer ts.Close()
+
+	// a closed channel never blocks, so the rate limiter doesn't slow the test down
+	noLimit := make(chan time.Time)
+	close(noLimit)
+
+	tests := []struct {
+		name      string
+		dataSaver bool
+		quality   string
+		firstURL  string
+	}{
+		{
+			name:     "original images",
+			quality:  QualityOriginal,
+			firstURL: "https://node.example.org/data/abc/1.png",
+		},
+		{
+			name:      "data saver images",
+			dataSaver: true,
+			quality:   QualityDataSaver,
+			firstURL:  "https://node.example.org/data-saver/abc/1.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMangadx(&Grabber{Settings: Settings{DataSaver: tt.dataSaver}})
+			m.ApiUrl = ts.URL
+			m.rateLimiter = noLimit
+
+			chapter, err := m.FetchChapter(&MangadxChapter{Chapter: Chapter{Number: 1}, Id: "ch-1"})
+			if err != nil {
+				t.Fatalf("FetchChapter() error = %v", err)
+			}
+
+			if chapter.Quality != tt.quality {
+				t.Errorf("Quality = %v, want %v", chapter.Quality, tt.quality)
+			}
+
+			if len(chapter.Pages) != 2 || chapter.Pages[0].URL != tt.firstURL {
+				t.Errorf("Pages = %v, want first page %v", chapter.Pages, tt.firstURL)
+			}
+		})
+	}
+}
+
+func TestMangadex_Report(t *testing.T) {
+	var got []mangadxReport
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Method = %v, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %v, want application/json", ct)
+		}
+
+		var report mangadxReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("failed to decode report: %v", err)
+		}
+		got = append(got, report)
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{})
+	m.ReportUrl = ts.URL
+
+	reports := []ImageReport{
+		{URL: "https://node.example.org/data/abc/1.png", Success: true, Bytes: 1024, Duration: 1500 * time.Millisecond, Cached: true},
+		{URL: "https://uploads.mangadex.org/data/abc/1.png", Success: true, Bytes: 1024},
+		{URL: "https://mangadex.org/covers/abc/1.png", Success: false},
+	}
+	for _, r := range reports {
+		if err := m.Report(r); err != nil {
+			t.Fatalf("Report() error = %v", err)
+		}
+	}
+
+	want := []mangadxReport{
+		{Url: "https://node.example.org/data/abc/1.png", Success: true, Bytes: 1024, Duration: 1500, Cached: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reports = %+v, want %+v", got, want)
+	}
+}
+
+func TestMangadex_RefreshChapter(t *testing.T) {
+	var forced []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forced = append(forced, r.URL.Query().Get("forcePort443"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"baseUrl": "https://node.example.org",
+			"chapter": {"hash": "abc", "data": ["1.png", "2.png"]}
+		}`))
+	}))
+	defer ts.Close()
+
+	noLimit := make(chan time.Time)
+	close(noLimit)
+
+	m := NewMangadx(&Grabber{})
+	m.ApiUrl = ts.URL
+	m.UploadsUrl = "https://uploads.example.org"
+	m.rateLimiter = noLimit
+
+	chapter, err := m.FetchChapter(&MangadxChapter{Chapter: Chapter{Number: 1}, Id: "ch-1"})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	wantURLs := []string{
+		"https://node.example.org/data/abc/2.png",
+		"https://node.example.org/data/abc/2.png",
+		"https://uploads.example.org/data/abc/2.png",
+	}
+	for attempt, want := range wantURLs {
+		refreshed, err := m.RefreshChapter(chapter, attempt)
+		if err != nil {
+			t.Fatalf("RefreshChapter(%d) error = %v", attempt, err)
+		}
+		if len(refreshed.Pages) != 2 || refreshed.Pages[1].URL != want {
+			t.Errorf("RefreshChapter(%d) pages = %v, want second page %v", attempt, refreshed.Pages, want)
+		}
+	}
+
+	if got := strings.Join(forced, ","); got != ",,true," {
+		t.Errorf("forcePort443 params = %q, want only the second refresh to force port 443", got)
+	}
+
+	if _, err := m.RefreshChapter(chapter, 3); !errors.Is(err, ErrNoImageSources) {
+		t.Errorf("RefreshChapter(3) error = %v, want ErrNoImageSources", err)
+	}
+
+	if _, err := m.RefreshChapter(&Chapter{Number: 1}, 0); !errors.Is(err, ErrNoImageSources) {
+		t.Errorf("RefreshChapter() error = %v for a chapter without id, want ErrNoImageSources", err)
+	}
+
+	// the uploads server is reached from the known pages when the at-home API is down
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if _, err := m.RefreshChapter(chapter, 1); err == nil {
+		t.Error("RefreshChapter(1) expected error while the at-home API is down, but got none")
+	}
+	refreshed, err := m.RefreshChapter(chapter, 2)
+	if err != nil {
+		t.Fatalf("RefreshChapter(2) error = %v while the at-home API is down", err)
+	}
+	if len(refreshed.Pages) != 2 || refreshed.Pages[1].URL != "https://uploads.example.org/data/abc/2.png" {
+		t.Errorf("RefreshChapter(2) pages = %v, want them on the uploads server", refreshed.Pages)
+	}
+}
+
+func TestMangadex_FetchCovers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/manga/a1c7c817-4e59-43b7-9365-09675a149a6f":
+			if r.URL.Query().Get("includes[]") != "cover_art" {
+				t.Errorf("includes[] = %v, want cover_art", r.URL.Query().Get("includes[]"))
+			}
+			w.Write([]byte(`{"data": {"relationships": [
+				{"id": "author-1", "type": "author"},
+				{"id": "cover-1", "type": "cover_art", "attributes": {"fileName": "main.jpg"}}
+			]}}`))
+		case "/cover":
+			w.Write([]byte(`{"data": [
+				{"attributes": {"volume": "1", "fileName": "v1-ja.jpg"}},
+				{"attributes": {"volume": "1", "fileName": "v1-en.jpg"}},
+				{"attributes": {"volume": null, "fileName": "none.jpg"}},
+				{"attributes": {"volume": "2", "fileName": "v2.jpg"}}
+			]}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{URL: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"})
+	m.ApiUrl = ts.URL
+	m.UploadsUrl = "https://uploads.example.org"
+
+	covers, err := m.FetchCovers()
+	if err != nil {
+		t.Fatalf("FetchCovers() error = %v", err)
+	}
+
+	base := "https://uploads.example.org/covers/a1c7c817-4e59-43b7-9365-09675a149a6f/"
+	want := []Cover{
+		{URL: base + "main.jpg"},
+		{Volume: "1", URL: base + "v1-ja.jpg"},
+		{Volume: "2", URL: base + "v2.jpg"},
+	}
+	if !reflect.DeepEqual(covers, want) {
+		t.Errorf("FetchCovers() = %v, want %v", covers, want)
+	}
+}
+
+func TestCoverFor(t *testing.T) {
+	covers := []Cover{
+		{Volume: "1", URL: "v1.jpg"},
+		{URL: "series.jpg"},
+	}
+
+	tests := []struct {
+		volume string
+		want   string
+		found  bool
+	}{
+		{volume: "1", want: "v1.jpg", found: true},
+		{volume: "2", want: "series.jpg", found: true},
+		{volume: "", want: "series.jpg", found: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := CoverFor(covers, tt.volume)
+		if ok != tt.found || got.URL != tt.want {
+			t.Errorf("CoverFor(%q) = %v, %v, want %v, %v", tt.volume, got.URL, ok, tt.want, tt.found)
+		}
+	}
+
+	if _, ok := CoverFor(covers[:1], "2"); ok {
+		t.Error("CoverFor() found a cover for a volume without cover and no series cover")
+	}
+}
+
+func TestMangadex_FetchMetadata(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.URL.Query()["includes[]"]; strings.Join(got, ",") != "cover_art,author,artist" {
+			t.Errorf("includes[] = %v, want cover_art, author and artist", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {
+			"attributes": {
+				"title": {"en": "Test Manga"},
+				"description": {"en": "An english description", "es": "Una descripción"},
+				"status": "ongoing",
+				"year": 1997,
+				"publicationDemographic": "shounen",
+				"originalLanguage": "ja",
+				"contentRating": "safe",
+				"links": {"al": "30013", "raw": "https://example.jp/raw", "unknown": "x"},
+				"tags": [
+					{"attributes": {"name": {"en": "Action"}, "group": "genre"}},
+					{"attributes": {"name": {"en": "Pirates"}, "group": "theme"}}
+				]
+			},
+			"relationships": [
+				{"id": "a1", "type": "author", "attributes": {"name": "Oda Eiichiro"}},
+				{"id": "a2", "type": "artist", "attributes": {"name": "Oda Eiichiro"}}
+			]
+		}}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{
+		URL:      "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece",
+		Settings: Settings{Languages: []string{"es", "en"}},
+	})
+	m.ApiUrl = ts.URL
+
+	meta, err := m.FetchMetadata()
+	if err != nil {
+		t.Fatalf("FetchMetadata() error = %v", err)
+	}
+
+	want := &SeriesMetadata{
+		Title:            "Test Manga",
+		Description:      "Una descripción",
+		Authors:          []string{"Oda Eiichiro"},
+		Artists:          []string{"Oda Eiichiro"},
+		Genres:           []string{"Action"},
+		Tags:             []string{"Pirates"},
+		Status:           "ongoing",
+		Year:             1997,
+		Demographic:      "shounen",
+		OriginalLanguage: "ja",
+		ContentRating:    "safe",
+		Links: map[string]string{
+			"AniList": "https://anilist.co/manga/30013",
+			"Raw":     "https://example.jp/raw",
+		},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("FetchMetadata() = %+v, want %+v", meta, want)
+	}
+
+	// the manga is requested once and shared with the title lookup
+	if title, err := m.FetchTitle(); err != nil || title != "Test Manga" {
+		t.Errorf("FetchTitle() = %q, %v, want %q", title, err, "Test Manga")
+	}
+	if requests != 1 {
+		t.Errorf("manga requested %d times, want once", requests)
+	}
+}
+
+func TestMangadex_ContentRating(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"attributes": {"title": {"en": "Rated"}, "contentRating": "pornographic"}}}`))
+	}))
+	defer ts.Close()
+
+	url := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/rated"
+
+	m := NewMangadx(&Grabber{URL: url})
+	m.ApiUrl = ts.URL
+
+	_, err := m.FetchTitle()
+	var rating *ContentRatingError
+	if !errors.As(err, &rating) || rating.Rating != "pornographic" {
+		t.Fatalf("FetchTitle() error = %v, want a ContentRatingError", err)
+	}
+
+	m = NewMangadx(&Grabber{URL: url, Settings: Settings{ContentRatings: ContentRatings}})
+	m.ApiUrl = ts.URL
+
+	if title, err := m.FetchTitle(); err != nil || title != "Rated" {
+		t.Errorf("FetchTitle() = %v, %v, want the title once its rating is allowed", title, err)
+	}
+}
+
+// staticToken is a TokenSource handing out a fixed token
+type staticToken string
+
+func (s staticToken) Token() (string, error) { return string(s), nil }
+
+func TestMangadex_Auth(t *testing.T) {
+	var authorization []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"attributes": {"title": {"en": "Test"}}}}`))
+	}))
+	defer ts.Close()
+
+	for _, auth := range []TokenSource{nil, staticToken("token")} {
+		m := NewMangadx(&Grabber{
+			URL:      "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+			Settings: Settings{Auth: auth},
+		})
+		m.ApiUrl = ts.URL
+		if _, err := m.FetchTitle(); err != nil {
+			t.Fatalf("FetchTitle() error = %v", err)
+		}
+	}
+
+	if !reflect.DeepEqual(authorization, []string{"", "Bearer token"}) {
+		t.Errorf("Authorization headers = %q, want none when anonymous and the bearer token when logged in", authorization)
+	}
+}
+
+func TestMangadex_FetchFollowedChapters(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/follows/manga/feed" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("followed feed requested without the session token")
+		}
+		if since := r.URL.Query().Get("createdAtSince"); since != "2024-01-31T12:00:00" {
+			t.Errorf("createdAtSince = %q, want 2024-01-31T12:00:00", since)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"total": 3,
+			"data": [
+				{"id": "ch-2", "attributes": {"chapter": "2", "translatedLanguage": "en"},
+				 "relationships": [{"id": "manga-1", "type": "manga", "attributes": {"title": {"en": "First"}}}]},
+				{"id": "ch-9", "attributes": {"chapter": "9", "translatedLanguage": "en"},
+				 "relationships": [{"id": "manga-2", "type": "manga", "attributes": {"title": {"ja": "Second"}}}]},
+				{"id": "ch-1", "attributes": {"chapter": "1", "translatedLanguage": "en"},
+				 "relationships": [{"id": "manga-1", "type": "manga", "attributes": {"title": {"en": "First"}}}]}
+			]
+		}`))
+	}))
+	defer ts.Close()
+
+	since := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	m := NewMangadx(&Grabber{})
+	m.ApiUrl = ts.URL
+	if _, err := m.FetchFollowedChapters(since); !errors.Is(err, ErrLoginRequired) {
+		t.Fatalf("FetchFollowedChapters() error = %v without a session, want ErrLoginRequired", err)
+	}
+
+	m.Settings.Auth = staticToken("token")
+	followed, err := m.FetchFollowedChapters(since)
+	if err != nil {
+		t.Fatalf("FetchFollowedChapters() error = %v", err)
+	}
+
+	if len(followed) != 2 {
+		t.Fatalf("FetchFollowedChapters() returned %d series, want 2", len(followed))
+	}
+
+	first := followed[0]
+	if first.Title != "First" || first.URL != "https://mangadex.org/title/manga-1" {
+		t.Errorf("first series = %s %s, want First at its MangaDex URL", first.Title, first.URL)
+	}
+	if len(first.Chapters) != 2 || first.Chapters[0].GetLabel() != "1" || first.Chapters[1].GetLabel() != "2" {
+		t.Errorf("first series chapters = %v, want chapters 1 and 2 in order", first.Chapters)
+	}
+
+	if followed[1].Title != "Second" || len(followed[1].Chapters) != 1 {
+		t.Errorf("second series = %+v, want Second with a single chapter", followed[1])
+	}
+}
+
+func TestMangadex_ReadMarkers(t *testing.T) {
+	var marked mangadxReadMarkers
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manga/a1c7c817-4e59-43b7-9365-09675a149a6f/read" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("read markers requested without the session token")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			json.NewDecoder(r.Body).Decode(&marked)
+			w.Write([]byte(`{"result": "ok"}`))
+			return
+		}
+		w.Write([]byte(`{"result": "ok", "data": ["ch-1", "ch-3"]}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{URL: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f"})
+	m.ApiUrl = ts.URL
+
+	chapters := Filterables{
+		&MangadxChapter{Chapter: Chapter{Number: 1, Label: "1"}, Id: "ch-1"},
+		&MangadxChapter{Chapter: Chapter{Number: 2, Label: "2"}, Id: "ch-2"},
+	}
+
+	if _, err := m.ReadChapters(chapters); !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("ReadChapters() error = %v without a session, want ErrLoginRequired", err)
+	}
+
+	m.Settings.Auth = staticToken("token")
+	read, err := m.ReadChapters(chapters)
+	if err != nil {
+		t.Fatalf("ReadChapters() error = %v", err)
+	}
+	if len(read) != 1 || read[0] != chapters[0] {
+		t.Errorf("ReadChapters() = %v, want only chapter 1", read)
+	}
+
+	// fetched chapters carry the id of the listed chapter
+	if err = m.MarkRead(Filterables{chapters[1], &Chapter{Number: 3, id: "ch-3"}}); err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+	if !reflect.DeepEqual(marked.ChapterIdsRead, []string{"ch-2", "ch-3"}) || marked.ChapterIdsUnread == nil {
+		t.Errorf("MarkRead() posted %+v, want chapters ch-2 and ch-3 read", marked)
+	}
+}
+
+func TestMangadex_FetchList(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/list/") {
+			w.Write([]byte(`{"data": {"attributes": {"name": "Reading Group"}, "relationships": [
+				{"id": "b3a7c817-4e59-43b7-9365-09675a149a6f", "type": "manga"},
+				{"id": "user-1", "type": "user"},
+				{"id": "a1c7c817-4e59-43b7-9365-09675a149a6f", "type": "manga"},
+				{"id": "c0000000-4e59-43b7-9365-09675a149a6f", "type": "manga"}
+			]}}`))
+			return
+		}
+
+		if ids := r.URL.Query()["ids[]"]; len(ids) != 3 {
+			t.Errorf("titles requested by ids %v, want the 3 manga of the list", ids)
+		}
+		if ratings := r.URL.Query()["contentRating[]"]; len(ratings) != len(ContentRatings) {
+			t.Errorf("titles requested with content ratings %v, want every rating", ratings)
+		}
+		w.Write([]byte(`{"data": [
+			{"id": "a1c7c817-4e59-43b7-9365-09675a149a6f", "attributes": {"title": {"en": "One Piece"}}},
+			{"id": "b3a7c817-4e59-43b7-9365-09675a149a6f", "attributes": {"title": {"en": "Berserk"}}}
+		]}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{URL: "https://mangadex.org/list/1fb1a5c5-7a94-4cb9-9d48-ee2c51a0d1c9/reading-group"})
+	m.ApiUrl = ts.URL
+
+	if _, err := m.FetchTitle(); err == nil || !strings.Contains(err.Error(), "list URL") {
+		t.Errorf("FetchTitle() error = %v, want a list URL to be rejected", err)
+	}
+
+	list, err := m.FetchList()
+	if err != nil {
+		t.Fatalf("FetchList() error = %v", err)
+	}
+
+	if list.Name != "Reading Group" {
+		t.Errorf("Name = %q, want %q", list.Name, "Reading Group")
+	}
+
+	var titles []string
+	for _, title := range list.Titles {
+		titles = append(titles, title.Title)
+	}
+	expected := []string{"Berserk", "One Piece", "c0000000-4e59-43b7-9365-09675a149a6f"}
+	if !reflect.DeepEqual(titles, expected) {
+		t.Errorf("Titles = %v, want %v in list order", titles, expected)
+	}
+
+	if list.Titles[1].URL != "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f" {
+		t.Errorf("URL = %q, want the title URL", list.Titles[1].URL)
+	}
+}
+
+func TestMangadex_Classify(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected URLKind
+	}{
+		{url: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece", expected: TitleURL},
+		{url: "https://mangadex.org/chapter/5e8bc984-5f3f-4fb1-b6ee-cf7f3812b112/1", expected: ChapterURL},
+		{url: "https://mangadex.org/list/1fb1a5c5-7a94-4cb9-9d48-ee2c51a0d1c9/reading-group", expected: ListURL},
+		{url: "https://mangadex.org/group/9a414441-bbad-43f1-a3a7-dc262ca790a3/group", expected: GroupURL},
+		{url: "https://mangadex.org/a1c7c817-4e59-43b7-9365-09675a149a6f", expected: TitleURL},
+	}
+
+	for _, tt := range tests {
+		m := NewMangadx(&Grabber{URL: tt.url})
+		if kind := m.Classify(); kind != tt.expected {
+			t.Errorf("Classify(%s) = %v, want %v", tt.url, kind, tt.expected)
+		}
+	}
+}
+
+func TestMangadex_ResolveChapter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chapter/5e8bc984-5f3f-4fb1-b6ee-cf7f3812b112" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"id": "5e8bc984-5f3f-4fb1-b6ee-cf7f3812b112",
+			"attributes": {"chapter": "12.5", "volume": "2", "title": "Side Story", "translatedLanguage": "en"},
+			"relationships": [
+				{"id": "group-1", "type": "scanlation_group", "attributes": {"name": "Alpha Scans"}},
+				{"id": "a1c7c817-4e59-43b7-9365-09675a149a6f", "type": "manga"}
+			]}}`))
+	}))
+	defer ts.Close()
+
+	m := NewMangadx(&Grabber{URL: "https://mangadex.org/chapter/5e8bc984-5f3f-4fb1-b6ee-cf7f3812b112"})
+	m.ApiUrl = ts.URL
+
+	titleURL, chapter, err := m.ResolveChapter()
+	if err != nil {
+		t.Fatalf("ResolveChapter() error = %v", err)
+	}
+
+	if titleURL != "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f" {
+		t.Errorf("title URL = %q, want the URL of the manga of the chapter", titleURL)
+	}
+
+	c := chapter.(*MangadxChapter)
+	if c.Id != "5e8bc984-5f3f-4fb1-b6ee-cf7f3812b112" || c.Label != "12.5" || c.Number != 12.5 || c.Volume != "2" {
+		t.Errorf("chapter = %+v, want chapter 12.5 of volume 2", c)
+	}
+	if len(c.Groups) != 1 || c.Groups[0] != "Alpha Scans" {
+		t.Errorf("Groups = %v, want [Alpha Scans]", c.Groups)
+	}
+}
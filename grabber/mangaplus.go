@@ -0,0 +1,318 @@
+package grabber
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/http"
+)
+
+// MangaPlus is a grabber for MANGA Plus by SHUEISHA, whose API answers in protobuf
+type MangaPlus struct {
+	*Grabber
+	// ApiUrl is the base URL of the MANGA Plus API
+	ApiUrl string
+	detail protoMessage
+}
+
+func init() {
+	Register("MANGA Plus", mangaPlusUrlRe.MatchString, func(g *Grabber) GrabberInterface {
+		return NewMangaPlus(g)
+	})
+}
+
+// mangaPlusApiUrl is the default base URL of the MANGA Plus API
+const mangaPlusApiUrl = "https://jumpg-webapi.tokyo-cdn.com/api"
+
+// mangaPlusUrlRe matches MANGA Plus title URLs
+var mangaPlusUrlRe = regexp.MustCompile(`mangaplus\.shueisha\.co\.jp/titles/(\d+)`)
+
+// mangaPlusLanguages maps the languages of the API to ISO codes, in the order of the API enum
+var mangaPlusLanguages = []string{"en", "es", "fr", "id", "pt-br", "ru", "th", "de", "", "vi"}
+
+// Field numbers of the MANGA Plus protobuf messages
+const (
+	// Response
+	mpSuccess = 1
+	mpError   = 2
+	// SuccessResult
+	mpTitleDetail = 8
+	mpViewer      = 10
+	// ErrorResult and its Popup
+	mpEnglishPopup = 2
+	mpPopupSubject = 1
+	mpPopupBody    = 2
+	// TitleDetailView and its ChapterGroup
+	mpTitle         = 1
+	mpFirstChapters = 9
+	mpLastChapters  = 10
+	mpChapterGroups = 28
+	mpGroupFirst    = 2
+	mpGroupMid      = 3
+	mpGroupLast     = 4
+	// Title
+	mpTitleName     = 2
+	mpTitleLanguage = 7
+	// Chapter
+	mpChapterId       = 2
+	mpChapterName     = 3
+	mpChapterSubTitle = 4
+	mpChapterVertical = 9
+	// MangaViewer, its Page and MangaPage
+	mpViewerPages       = 1
+	mpMangaPage         = 1
+	mpPageImageUrl      = 1
+	mpPageEncryptionKey = 5
+)
+
+func NewMangaPlus(g *Grabber) *MangaPlus {
+	return &MangaPlus{Grabber: g, ApiUrl: mangaPlusApiUrl}
+}
+
+// MangaPlusChapter represents a MANGA Plus chapter
+type MangaPlusChapter struct {
+	Chapter
+	Id string
+}
+
+// GetId returns the MANGA Plus id of the chapter
+func (c MangaPlusChapter) GetId() string {
+	return c.Id
+}
+
+// Test checks if the URL is a MANGA Plus title
+func (m *MangaPlus) Test() (bool, error) {
+	return mangaPlusUrlRe.MatchString(m.URL), nil
+}
+
+// FetchTitle returns the name of the title
+func (m *MangaPlus) FetchTitle() (string, error) {
+	detail, err := m.titleDetail()
+	if err != nil {
+		return "", err
+	}
+
+	title, err := detail.message(mpTitle)
+	if err != nil {
+		return "", err
+	}
+	name := title.string(mpTitleName)
+	if name == "" {
+		return "", fmt.Errorf("no title found at %s", m.URL)
+	}
+
+	return name, nil
+}
+
+// FetchChapters returns the chapters listed on the title page. MANGA Plus only keeps the first and latest chapters
+// of a series readable, so the chapters in between are not listed.
+func (m *MangaPlus) FetchChapters() (Filterables, []error) {
+	detail, err := m.titleDetail()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	title, err := detail.message(mpTitle)
+	if err != nil {
+		return nil, []error{err}
+	}
+	language := ""
+	if lang := title.uint(mpTitleLanguage); lang < uint64(len(mangaPlusLanguages)) {
+		language = mangaPlusLanguages[lang]
+	}
+
+	lists, err := mangaPlusChapterLists(detail)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var chapters Filterables
+	seen := make(map[string]bool)
+	for _, ch := range lists {
+		id := strconv.FormatUint(ch.uint(mpChapterId), 10)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		chapters = append(chapters, mangaPlusChapter(id, ch, language))
+	}
+
+	SortChapters(chapters)
+	return chapters, nil
+}
+
+// mangaPlusChapterLists returns the chapters of a title detail, which are grouped by ranges of chapters in the
+// current API and split in first and last chapters in the older one
+func mangaPlusChapterLists(detail protoMessage) ([]protoMessage, error) {
+	groups, err := detail.messages(mpChapterGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapters []protoMessage
+	if len(groups) == 0 {
+		for _, list := range []int{mpFirstChapters, mpLastChapters} {
+			chs, err := detail.messages(list)
+			if err != nil {
+				return nil, err
+			}
+			chapters = append(chapters, chs...)
+		}
+		return chapters, nil
+	}
+
+	for _, group := range groups {
+		for _, list := range []int{mpGroupFirst, mpGroupMid, mpGroupLast} {
+			chs, err := group.messages(list)
+			if err != nil {
+				return nil, err
+			}
+			chapters = append(chapters, chs...)
+		}
+	}
+	return chapters, nil
+}
+
+// mangaPlusChapter returns the chapter of a chapter message, chapter names are like "#012" for numbered chapters
+// and "ex" or "One-shot" otherwise
+func mangaPlusChapter(id string, ch protoMessage, language string) *MangaPlusChapter {
+	name := strings.TrimSpace(ch.string(mpChapterName))
+
+	chapter := Chapter{
+		Label:     name,
+		Title:     strings.TrimSpace(ch.string(mpChapterSubTitle)),
+		Language:  language,
+		LongStrip: ch.bool(mpChapterVertical),
+	}
+	if num, err := strconv.ParseFloat(strings.TrimPrefix(name, "#"), 64); err == nil {
+		chapter.Number = num
+		chapter.Label = strconv.FormatFloat(num, 'f', -1, 64)
+	}
+
+	return &MangaPlusChapter{Chapter: chapter, Id: id}
+}
+
+// FetchChapter fetches the pages of a chapter, page images are encrypted with a key given along with each page
+func (m *MangaPlus) FetchChapter(f Filterable) (*Chapter, error) {
+	chap := f.(*MangaPlusChapter)
+
+	success, err := m.apiGet("manga_viewer?split=yes&img_quality=super_high&chapter_id=" + chap.Id)
+	if err != nil {
+		return nil, err
+	}
+	viewer, err := success.message(mpViewer)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := viewer.messages(mpViewerPages)
+	if err != nil {
+		return nil, err
+	}
+
+	chapter := &Chapter{
+		Title:     chap.Title,
+		Number:    chap.Number,
+		Label:     chap.Label,
+		Language:  chap.Language,
+		LongStrip: chap.LongStrip,
+		id:        chap.Id,
+	}
+	for _, page := range pages {
+		// banners and the last page with links to other chapters are not manga pages
+		mangaPage, err := page.message(mpMangaPage)
+		if err != nil {
+			return nil, err
+		}
+		image := mangaPage.string(mpPageImageUrl)
+		if image == "" {
+			continue
+		}
+		chapter.Pages = append(chapter.Pages, Page{
+			Number:        int64(len(chapter.Pages) + 1),
+			URL:           image,
+			EncryptionKey: mangaPage.string(mpPageEncryptionKey),
+		})
+	}
+
+	if len(chapter.Pages) == 0 {
+		return nil, fmt.Errorf("no pages found for chapter %s, it may have expired", chap.Label)
+	}
+	chapter.PagesCount = int64(len(chapter.Pages))
+
+	return chapter, nil
+}
+
+// titleDetail returns the title detail view of the title, it's requested once and shared by FetchTitle and
+// FetchChapters
+func (m *MangaPlus) titleDetail() (protoMessage, error) {
+	if m.detail != nil {
+		return m.detail, nil
+	}
+
+	match := mangaPlusUrlRe.FindStringSubmatch(m.URL)
+	if match == nil {
+		return nil, fmt.Errorf("%s is not a MANGA Plus title URL", m.URL)
+	}
+
+	success, err := m.apiGet("title_detailV3?title_id=" + match[1])
+	if err != nil {
+		return nil, err
+	}
+	detail, err := success.message(mpTitleDetail)
+	if err != nil {
+		return nil, err
+	}
+	if detail == nil {
+		return nil, fmt.Errorf("no title found at %s", m.URL)
+	}
+
+	m.detail = detail
+	return detail, nil
+}
+
+// apiGet requests an API endpoint, returning the success result of the response
+func (m *MangaPlus) apiGet(endpoint string) (protoMessage, error) {
+	rbody, err := http.Get(http.RequestParams{
+		URL:     m.ApiUrl + "/" + endpoint,
+		Referer: "https://mangaplus.shueisha.co.jp/",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rbody.Close()
+
+	body, err := io.ReadAll(rbody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := decodeProto(body)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding MANGA Plus response: %w", err)
+	}
+
+	if _, failed := resp.last(mpError); failed {
+		result, err := resp.message(mpError)
+		if err != nil {
+			return nil, err
+		}
+		popup, err := result.message(mpEnglishPopup)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("MANGA Plus error: %s: %s", popup.string(mpPopupSubject), popup.string(mpPopupBody))
+	}
+
+	success, err := resp.message(mpSuccess)
+	if err != nil {
+		return nil, err
+	}
+	if success == nil {
+		return nil, fmt.Errorf("empty MANGA Plus response from %s", endpoint)
+	}
+
+	return success, nil
+}
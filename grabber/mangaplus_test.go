@@ -0,0 +1,181 @@
+package grabber
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mangaPlusChapterProto encodes a chapter message
+func mangaPlusChapterProto(id uint64, name string, subTitle string) protoBuilder {
+	return protoBuilder{}.uint(1, 100020).uint(mpChapterId, id).string(mpChapterName, name).string(mpChapterSubTitle, subTitle)
+}
+
+// mangaPlusTitleDetail encodes a title detail response of a Spanish title, chapters are listed in chapter groups
+// like the current API does, the first chapter is listed twice
+func mangaPlusTitleDetail() []byte {
+	title := protoBuilder{}.uint(1, 100020).string(mpTitleName, "One Piece").uint(mpTitleLanguage, 1)
+	first := protoBuilder{}.
+		string(1, "1-3").
+		message(mpGroupFirst, mangaPlusChapterProto(1000001, "#001", "Romance Dawn")).
+		message(mpGroupFirst, mangaPlusChapterProto(1000002, "#002", "They Call Him Straw Hat Luffy"))
+	last := protoBuilder{}.
+		string(1, "1100-1101").
+		message(mpGroupFirst, mangaPlusChapterProto(1000001, "#001", "Romance Dawn")).
+		message(mpGroupLast, mangaPlusChapterProto(1001101, "#1101", "Bonney's Adventure")).
+		message(mpGroupLast, mangaPlusChapterProto(1001200, "ex", "Special Chapter").uint(mpChapterVertical, 1))
+
+	detail := protoBuilder{}.message(mpTitle, title).message(mpChapterGroups, first).message(mpChapterGroups, last)
+	return protoBuilder{}.message(mpSuccess, protoBuilder{}.message(mpTitleDetail, detail))
+}
+
+// mangaPlusServer serves MANGA Plus API responses
+func mangaPlusServer(t *testing.T, titleDetail []byte) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/title_detailV3", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("title_id") != "100020" {
+			t.Errorf("title requested with title_id %q, want 100020", r.URL.Query().Get("title_id"))
+		}
+		w.Write(titleDetail)
+	})
+	mux.HandleFunc("/api/manga_viewer", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chapter_id") != "1000002" {
+			popup := protoBuilder{}.string(mpPopupSubject, "Not Found").string(mpPopupBody, "This chapter has expired.")
+			w.Write(protoBuilder{}.message(mpError, protoBuilder{}.uint(1, 1).message(mpEnglishPopup, popup)))
+			return
+		}
+
+		page := func(url string, key string) protoBuilder {
+			return protoBuilder{}.message(mpMangaPage, protoBuilder{}.string(mpPageImageUrl, url).uint(2, 1120).uint(3, 1600).string(mpPageEncryptionKey, key))
+		}
+		lastPage := protoBuilder{}.message(3, protoBuilder{}.uint(1, 1000003))
+		viewer := protoBuilder{}.
+			message(mpViewerPages, page("https://mangaplus.shueisha.co.jp/drm/1.jpg", "a1b2c3")).
+			message(mpViewerPages, page("https://mangaplus.shueisha.co.jp/drm/2.jpg", "d4e5f6")).
+			message(mpViewerPages, lastPage).
+			uint(2, 1000002)
+		w.Write(protoBuilder{}.message(mpSuccess, protoBuilder{}.message(mpViewer, viewer)))
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestMangaPlus(ts *httptest.Server) *MangaPlus {
+	m := NewMangaPlus(&Grabber{URL: "https://mangaplus.shueisha.co.jp/titles/100020"})
+	m.ApiUrl = ts.URL + "/api"
+	return m
+}
+
+func TestMangaPlus_Test(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "https://mangaplus.shueisha.co.jp/titles/100020", expected: true},
+		{url: "https://mangaplus.shueisha.co.jp/viewer/1000486", expected: false},
+		{url: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f", expected: false},
+	}
+
+	for _, tt := range tests {
+		if ok, _ := NewMangaPlus(&Grabber{URL: tt.url}).Test(); ok != tt.expected {
+			t.Errorf("Test(%s) = %v, want %v", tt.url, ok, tt.expected)
+		}
+	}
+}
+
+func TestMangaPlus_FetchChapters(t *testing.T) {
+	m := newTestMangaPlus(mangaPlusServer(t, mangaPlusTitleDetail()))
+
+	title, err := m.FetchTitle()
+	if err != nil {
+		t.Fatalf("FetchTitle() error = %v", err)
+	}
+	if title != "One Piece" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "One Piece")
+	}
+
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+
+	var labels, ids []string
+	for _, ch := range chapters {
+		chap := ch.(*MangaPlusChapter)
+		labels = append(labels, chap.Label)
+		ids = append(ids, chap.Id)
+		if chap.Language != "es" {
+			t.Errorf("chapter %s language = %q, want es", chap.Label, chap.Language)
+		}
+	}
+	if expected := []string{"1", "2", "1101", "ex"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("FetchChapters() labels = %v, want %v", labels, expected)
+	}
+	if expected := []string{"1000001", "1000002", "1001101", "1001200"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("FetchChapters() ids = %v, want %v", ids, expected)
+	}
+
+	first := chapters[0].(*MangaPlusChapter)
+	if first.Number != 1 || first.Title != "Romance Dawn" || first.LongStrip {
+		t.Errorf("FetchChapters() first chapter = %+v, want chapter 1 Romance Dawn", first.Chapter)
+	}
+	if extra := chapters[3].(*MangaPlusChapter); !extra.LongStrip {
+		t.Errorf("FetchChapters() extra chapter LongStrip = false, want the vertical only chapter to be a long strip")
+	}
+}
+
+func TestMangaPlus_FetchChaptersFirstAndLastLists(t *testing.T) {
+	title := protoBuilder{}.uint(1, 100020).string(mpTitleName, "One Piece")
+	detail := protoBuilder{}.
+		message(mpTitle, title).
+		message(mpFirstChapters, mangaPlusChapterProto(1000001, "#001", "Romance Dawn")).
+		message(mpLastChapters, mangaPlusChapterProto(1001101, "#1101", "Bonney's Adventure"))
+
+	m := newTestMangaPlus(mangaPlusServer(t, protoBuilder{}.message(mpSuccess, protoBuilder{}.message(mpTitleDetail, detail))))
+
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+	if len(chapters) != 2 || chapters[0].GetNumber() != 1 || chapters[1].GetNumber() != 1101 {
+		t.Errorf("FetchChapters() = %v, want chapters 1 and 1101", chapters)
+	}
+	if chapters[0].GetLanguage() != "en" {
+		t.Errorf("FetchChapters() language = %q, want the default en", chapters[0].GetLanguage())
+	}
+}
+
+func TestMangaPlus_FetchChapter(t *testing.T) {
+	m := newTestMangaPlus(mangaPlusServer(t, mangaPlusTitleDetail()))
+
+	chapter, err := m.FetchChapter(&MangaPlusChapter{Chapter: Chapter{Number: 2, Label: "2", Language: "es"}, Id: "1000002"})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	expected := []Page{
+		{Number: 1, URL: "https://mangaplus.shueisha.co.jp/drm/1.jpg", EncryptionKey: "a1b2c3"},
+		{Number: 2, URL: "https://mangaplus.shueisha.co.jp/drm/2.jpg", EncryptionKey: "d4e5f6"},
+	}
+	if !reflect.DeepEqual(chapter.Pages, expected) {
+		t.Errorf("FetchChapter() pages = %+v, want %+v", chapter.Pages, expected)
+	}
+	if chapter.PagesCount != 2 || chapter.Label != "2" || chapter.Language != "es" {
+		t.Errorf("FetchChapter() = %+v, want the chapter with its 2 manga pages", chapter)
+	}
+}
+
+func TestMangaPlus_FetchChapterError(t *testing.T) {
+	m := newTestMangaPlus(mangaPlusServer(t, mangaPlusTitleDetail()))
+
+	_, err := m.FetchChapter(&MangaPlusChapter{Chapter: Chapter{Number: 3, Label: "3"}, Id: "1000003"})
+	if err == nil || !strings.Contains(err.Error(), "This chapter has expired.") {
+		t.Errorf("FetchChapter() error = %v, want the error popup of the API", err)
+	}
+}
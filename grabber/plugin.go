@@ -0,0 +1,324 @@
+package grabber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Plugin is an executable implementing a site in any language. For every call mango runs the executable, writes a
+// single PluginRequest as JSON to its standard input and reads a single PluginResponse from its standard output.
+// A plugin reports failures with the error field of the response or with a non zero exit status, in which case its
+// standard error output is the error message.
+type Plugin struct {
+	// Command is the path of the executable
+	Command string
+	// Args are passed to the executable on every call
+	Args []string
+	// Name is the site name given by the plugin
+	Name string
+	// match selects the URLs handed to the plugin, which is asked about every URL when it gives no pattern
+	match *regexp.Regexp
+}
+
+// Methods of the plugin protocol
+const (
+	PluginInfo          = "Info"
+	PluginTest          = "Test"
+	PluginFetchTitle    = "FetchTitle"
+	PluginFetchChapters = "FetchChapters"
+	PluginFetchChapter  = "FetchChapter"
+)
+
+// pluginTimeout is the longest a plugin may take to answer a call, pluginInfoTimeout the longest it may take to
+// describe itself since that involves no request to its site
+const (
+	pluginTimeout     = 2 * time.Minute
+	pluginInfoTimeout = 5 * time.Second
+)
+
+// PluginRequest is the call sent to a plugin
+type PluginRequest struct {
+	Method   string          `json:"method"`
+	URL      string          `json:"url,omitempty"`
+	Settings *PluginSettings `json:"settings,omitempty"`
+	// Chapter is the listed chapter whose pages are requested by FetchChapter
+	Chapter *PluginChapter `json:"chapter,omitempty"`
+}
+
+// PluginSettings are the settings a plugin is given to select chapters and images
+type PluginSettings struct {
+	Languages      []string `json:"languages,omitempty"`
+	DataSaver      bool     `json:"data_saver,omitempty"`
+	ContentRatings []string `json:"content_ratings,omitempty"`
+}
+
+// PluginResponse is the answer of a plugin, only the fields of the requested method are read
+type PluginResponse struct {
+	Error string `json:"error,omitempty"`
+	// Name and Match answer Info, Match is a regular expression of the URLs the plugin handles
+	Name  string `json:"name,omitempty"`
+	Match string `json:"match,omitempty"`
+	// OK answers Test
+	OK bool `json:"ok,omitempty"`
+	// Title answers FetchTitle
+	Title string `json:"title,omitempty"`
+	// Chapters answers FetchChapters
+	Chapters []PluginChapter `json:"chapters,omitempty"`
+	// Chapter answers FetchChapter, with its pages
+	Chapter *PluginChapter `json:"chapter,omitempty"`
+}
+
+// PluginChapter is a chapter exchanged with a plugin. Id is opaque to mango, it's handed back to the plugin to fetch
+// the pages of the chapter.
+type PluginChapter struct {
+	Id        string       `json:"id"`
+	Number    float64      `json:"number"`
+	Label     string       `json:"label,omitempty"`
+	Volume    string       `json:"volume,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	Language  string       `json:"language,omitempty"`
+	Groups    []string     `json:"groups,omitempty"`
+	LongStrip bool         `json:"long_strip,omitempty"`
+	Pages     []PluginPage `json:"pages,omitempty"`
+}
+
+// PluginPage is a page image of a chapter
+type PluginPage struct {
+	URL           string `json:"url"`
+	Referer       string `json:"referer,omitempty"`
+	EncryptionKey string `json:"encryption_key,omitempty"`
+}
+
+// LoadPlugin asks an executable for its site name and URL pattern
+func LoadPlugin(command string, args ...string) (*Plugin, error) {
+	p := &Plugin{Command: command, Args: args}
+
+	resp, err := p.call(PluginRequest{Method: PluginInfo})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Name == "" {
+		return nil, errors.New("plugin has no name")
+	}
+	p.Name = resp.Name
+
+	if resp.Match != "" {
+		if p.match, err = regexp.Compile(resp.Match); err != nil {
+			return nil, fmt.Errorf("invalid match pattern: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// LoadPlugins registers a site for every executable of the directory, a missing directory has no plugins. Plugins
+// failing to load are reported once every other one was registered.
+func LoadPlugins(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		p, err := LoadPlugin(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid plugin %s: %w", path, err))
+			continue
+		}
+
+		p.Register()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Register adds the site of the plugin to the registry
+func (p *Plugin) Register() {
+	Register(p.Name, p.Match, func(g *Grabber) GrabberInterface {
+		return NewPluginGrabber(p, g)
+	})
+}
+
+// Match reports whether the plugin handles the URL
+func (p *Plugin) Match(url string) bool {
+	return p.match == nil || p.match.MatchString(url)
+}
+
+// call runs the plugin with a request, returning its response
+func (p *Plugin) call(req PluginRequest) (*PluginResponse, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := pluginTimeout
+	if req.Method == PluginInfo {
+		timeout = pluginInfoTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %s: %w", req.Method, msg, err)
+		}
+		return nil, fmt.Errorf("plugin %s: %w", req.Method, err)
+	}
+
+	resp := &PluginResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", req.Method, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", req.Method, resp.Error)
+	}
+
+	return resp, nil
+}
+
+// PluginGrabber is a grabber for the site of a plugin
+type PluginGrabber struct {
+	*Grabber
+	plugin *Plugin
+}
+
+// NewPluginGrabber returns a grabber asking the plugin for the content of the URL
+func NewPluginGrabber(p *Plugin, g *Grabber) *PluginGrabber {
+	return &PluginGrabber{Grabber: g, plugin: p}
+}
+
+// PluginGrabberChapter is a chapter listed by a plugin
+type PluginGrabberChapter struct {
+	Chapter
+	Id string
+}
+
+// GetId returns the id the plugin gave to the chapter
+func (c PluginGrabberChapter) GetId() string {
+	return c.Id
+}
+
+// Test asks the plugin whether it handles the URL
+func (p *PluginGrabber) Test() (bool, error) {
+	resp, err := p.call(PluginTest, nil)
+	if err != nil {
+		return false, err
+	}
+	return resp.OK, nil
+}
+
+// FetchTitle asks the plugin for the title at the URL
+func (p *PluginGrabber) FetchTitle() (string, error) {
+	resp, err := p.call(PluginFetchTitle, nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Title, nil
+}
+
+// FetchChapters asks the plugin for the chapters of the title
+func (p *PluginGrabber) FetchChapters() (Filterables, []error) {
+	resp, err := p.call(PluginFetchChapters, nil)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	chapters := make(Filterables, 0, len(resp.Chapters))
+	for _, ch := range resp.Chapters {
+		chapters = append(chapters, &PluginGrabberChapter{Chapter: ch.chapter(), Id: ch.Id})
+	}
+
+	SortChapters(chapters)
+	return chapters, nil
+}
+
+// FetchChapter asks the plugin for the pages of a chapter
+func (p *PluginGrabber) FetchChapter(f Filterable) (*Chapter, error) {
+	chap := f.(*PluginGrabberChapter)
+
+	resp, err := p.call(PluginFetchChapter, &PluginChapter{
+		Id:        chap.Id,
+		Number:    chap.Number,
+		Label:     chap.Label,
+		Volume:    chap.Volume,
+		Title:     chap.Title,
+		Language:  chap.Language,
+		Groups:    chap.Groups,
+		LongStrip: chap.LongStrip,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Chapter == nil || len(resp.Chapter.Pages) == 0 {
+		return nil, fmt.Errorf("plugin %s returned no pages for chapter %s", p.plugin.Name, ChapterName(chap))
+	}
+
+	// the plugin only has to send the pages, the listed chapter describes the rest
+	chapter := chap.Chapter
+	chapter.Pages = nil
+	chapter.id = chap.Id
+	for _, page := range resp.Chapter.Pages {
+		chapter.Pages = append(chapter.Pages, Page{
+			Number:        int64(len(chapter.Pages) + 1),
+			URL:           page.URL,
+			Referer:       page.Referer,
+			EncryptionKey: page.EncryptionKey,
+		})
+	}
+	chapter.PagesCount = int64(len(chapter.Pages))
+
+	return &chapter, nil
+}
+
+// call runs a method of the plugin for the URL of the grabber
+func (p *PluginGrabber) call(method string, chapter *PluginChapter) (*PluginResponse, error) {
+	return p.plugin.call(PluginRequest{
+		Method: method,
+		URL:    p.URL,
+		Settings: &PluginSettings{
+			Languages:      p.Settings.LanguagePriority(),
+			DataSaver:      p.Settings.DataSaver,
+			ContentRatings: p.Settings.ContentRatingFilter(),
+		},
+		Chapter: chapter,
+	})
+}
+
+// chapter returns the chapter of a plugin chapter, without its pages. Chapters without a label are identified by
+// their number.
+func (c PluginChapter) chapter() Chapter {
+	return Chapter{
+		Number:    c.Number,
+		Label:     c.Label,
+		Volume:    c.Volume,
+		Title:     c.Title,
+		Language:  c.Language,
+		Groups:    c.Groups,
+		LongStrip: c.LongStrip,
+	}
+}
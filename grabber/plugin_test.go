@@ -0,0 +1,243 @@
+package grabber
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestHelperPlugin isn't a real test, it's the plugin run by the plugin tests: they run the test binary again with
+// MANGO_TEST_PLUGIN set so only this function answers the request.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("MANGO_TEST_PLUGIN") != "1" {
+		return
+	}
+
+	var req PluginRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "bad request: %v", err)
+		os.Exit(2)
+	}
+
+	var resp PluginResponse
+	switch req.Method {
+	case PluginInfo:
+		resp = PluginResponse{Name: "Helper Scans", Match: `example\.com/`}
+	case PluginTest:
+		resp = PluginResponse{OK: strings.Contains(req.URL, "/series/")}
+	case PluginFetchTitle:
+		resp = PluginResponse{Title: "Helper Manga"}
+	case PluginFetchChapters:
+		language := ""
+		if req.Settings != nil && len(req.Settings.Languages) > 0 {
+			language = req.Settings.Languages[0]
+		}
+		resp = PluginResponse{Chapters: []PluginChapter{
+			{Id: "c2", Number: 2, Label: "2", Title: "Two", Language: language, Groups: []string{"Helper Group"}},
+			{Id: "c1", Number: 1, Label: "1", Title: "One", Language: language, Volume: "1"},
+			{Id: "extra", Label: "Extra", Language: language, LongStrip: true},
+		}}
+	case PluginFetchChapter:
+		switch req.Chapter.Id {
+		case "c1":
+			resp = PluginResponse{Chapter: &PluginChapter{Pages: []PluginPage{
+				{URL: "https://img.example.com/c1/1.jpg", Referer: "https://example.com/"},
+				{URL: "https://img.example.com/c1/2.jpg", EncryptionKey: "ff"},
+			}}}
+		case "c2":
+			fmt.Fprint(os.Stderr, "chapter c2 is gone")
+			os.Exit(1)
+		default:
+			resp = PluginResponse{Error: "no pages for " + req.Chapter.Id}
+		}
+	default:
+		resp = PluginResponse{Error: "unknown method " + req.Method}
+	}
+
+	json.NewEncoder(os.Stdout).Encode(resp)
+	os.Exit(0)
+}
+
+// helperPlugin loads the test binary as a plugin
+func helperPlugin(t *testing.T) *Plugin {
+	t.Helper()
+	t.Setenv("MANGO_TEST_PLUGIN", "1")
+
+	p, err := LoadPlugin(os.Args[0], "-test.run=^TestHelperPlugin$")
+	if err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	return p
+}
+
+func TestLoadPlugin(t *testing.T) {
+	p := helperPlugin(t)
+
+	if p.Name != "Helper Scans" {
+		t.Errorf("LoadPlugin() name = %q, want %q", p.Name, "Helper Scans")
+	}
+	if !p.Match("https://example.com/series/helper") || p.Match("https://mangadex.org/title/x") {
+		t.Error("Match() doesn't follow the pattern given by the plugin")
+	}
+
+	ok, err := NewPluginGrabber(p, &Grabber{URL: "https://example.com/series/helper"}).Test()
+	if err != nil || !ok {
+		t.Errorf("Test() = %v, %v, want the plugin to accept the series", ok, err)
+	}
+	ok, err = NewPluginGrabber(p, &Grabber{URL: "https://example.com/about"}).Test()
+	if err != nil || ok {
+		t.Errorf("Test() = %v, %v, want the plugin to reject the page", ok, err)
+	}
+}
+
+func TestPluginGrabber_FetchChapters(t *testing.T) {
+	g := NewPluginGrabber(helperPlugin(t), &Grabber{
+		URL:      "https://example.com/series/helper",
+		Settings: Settings{Languages: []string{"es", "en"}},
+	})
+
+	title, err := g.FetchTitle()
+	if err != nil || title != "Helper Manga" {
+		t.Errorf("FetchTitle() = %q, %v, want %q", title, err, "Helper Manga")
+	}
+
+	chapters, errs := g.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+
+	var ids []string
+	for _, ch := range chapters {
+		ids = append(ids, ch.GetId())
+		if ch.GetLanguage() != "es" {
+			t.Errorf("chapter %s language = %q, want the settings to reach the plugin", ch.GetId(), ch.GetLanguage())
+		}
+	}
+	if expected := []string{"c1", "c2", "extra"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("FetchChapters() ids = %v, want %v", ids, expected)
+	}
+
+	first := chapters[0].(*PluginGrabberChapter)
+	if first.Volume != "1" || first.Title != "One" || !chapters[2].(*PluginGrabberChapter).LongStrip {
+		t.Errorf("FetchChapters() = %+v, want the chapters as given by the plugin", chapters)
+	}
+}
+
+func TestPluginGrabber_FetchChapter(t *testing.T) {
+	g := NewPluginGrabber(helperPlugin(t), &Grabber{URL: "https://example.com/series/helper"})
+
+	listed := &PluginGrabberChapter{Chapter: Chapter{Number: 1, Label: "1", Title: "One", Volume: "1"}, Id: "c1"}
+	chapter, err := g.FetchChapter(listed)
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	expected := []Page{
+		{Number: 1, URL: "https://img.example.com/c1/1.jpg", Referer: "https://example.com/"},
+		{Number: 2, URL: "https://img.example.com/c1/2.jpg", EncryptionKey: "ff"},
+	}
+	if !reflect.DeepEqual(chapter.Pages, expected) {
+		t.Errorf("FetchChapter() pages = %+v, want %+v", chapter.Pages, expected)
+	}
+	if chapter.Title != "One" || chapter.Volume != "1" || chapter.PagesCount != 2 || chapter.GetId() != "c1" {
+		t.Errorf("FetchChapter() = %+v, want the listed chapter with its pages", chapter)
+	}
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{id: "c2", expected: "chapter c2 is gone"},
+		{id: "c3", expected: "no pages for c3"},
+	}
+	for _, tt := range tests {
+		_, err := g.FetchChapter(&PluginGrabberChapter{Chapter: Chapter{Label: tt.id}, Id: tt.id})
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("FetchChapter(%s) error = %v, want %q", tt.id, err, tt.expected)
+		}
+	}
+}
+
+func TestLoadPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin wrappers are shell scripts")
+	}
+	withSites(t, nil)
+	t.Setenv("MANGO_TEST_PLUGIN", "1")
+
+	dir := t.TempDir()
+	write := func(name string, content string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("helper", fmt.Sprintf("#!/bin/sh\nexec %q -test.run='^TestHelperPlugin$'\n", os.Args[0]), 0755)
+	write("broken", "#!/bin/sh\necho not json\n", 0755)
+	write("README.md", "not a plugin", 0644)
+
+	err := LoadPlugins(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("LoadPlugins() error = %v, want the broken plugin reported", err)
+	}
+	if sites := Sites(); !reflect.DeepEqual(sites, []string{"Helper Scans"}) {
+		t.Fatalf("Sites() = %v, want the valid plugin registered", sites)
+	}
+
+	site, err := New(&Grabber{URL: "https://example.com/series/helper"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, ok := site.(*PluginGrabber); !ok {
+		t.Errorf("New() = %T, want *PluginGrabber", site)
+	}
+
+	if err := LoadPlugins(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("LoadPlugins() error = %v for a missing directory", err)
+	}
+}
+
+func TestLoadPlugins_FailingTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin wrappers are shell scripts")
+	}
+	withSites(t, nil)
+	t.Setenv("MANGO_TEST_PLUGIN", "1")
+
+	// the crashing plugin has no pattern, so it's asked about every URL before the helper
+	dir := t.TempDir()
+	crashing := "#!/bin/sh\ncase \"$(cat)\" in\n*Info*) echo '{\"name\": \"Crashing\"}' ;;\n*) echo crashed >&2; exit 1 ;;\nesac\n"
+	helper := fmt.Sprintf("#!/bin/sh\nexec %q -test.run='^TestHelperPlugin$'\n", os.Args[0])
+	for name, content := range map[string]string{"a-crashing": crashing, "b-helper": helper} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := LoadPlugins(dir); err != nil {
+		t.Fatalf("LoadPlugins() error = %v", err)
+	}
+	if sites := Sites(); !reflect.DeepEqual(sites, []string{"Crashing", "Helper Scans"}) {
+		t.Fatalf("Sites() = %v, want both plugins registered in order", sites)
+	}
+
+	site, err := New(&Grabber{URL: "https://example.com/series/helper"})
+	if err != nil {
+		t.Fatalf("New() error = %v, want the helper to handle the URL", err)
+	}
+	if site.(*PluginGrabber).plugin.Name != "Helper Scans" {
+		t.Errorf("New() = %s, want Helper Scans", site.(*PluginGrabber).plugin.Name)
+	}
+
+	// with no site accepting the URL the failure is told along with the supported sites
+	_, err = New(&Grabber{URL: "https://other.example.org/series/x"})
+	var unsupported *UnsupportedSiteError
+	if !errors.As(err, &unsupported) || !strings.Contains(err.Error(), "crashed") {
+		t.Errorf("New() error = %v, want the crash and an UnsupportedSiteError", err)
+	}
+}
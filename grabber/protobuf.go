@@ -0,0 +1,140 @@
+package grabber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// errProtoTruncated is returned when a protobuf message ends in the middle of a field
+var errProtoTruncated = errors.New("protobuf message is truncated")
+
+// protoField is a decoded protobuf field, Value holds varints and fixed numbers and Bytes holds length delimited
+// fields: strings, bytes and nested messages
+type protoField struct {
+	Number int
+	Wire   int
+	Value  uint64
+	Bytes  []byte
+}
+
+// protoMessage is a decoded protobuf message, fields are kept in wire order so repeated fields keep their order
+type protoMessage []protoField
+
+// decodeProto decodes the fields of a protobuf message without a schema, nested messages are left as bytes until
+// read with message or messages
+func decodeProto(data []byte) (protoMessage, error) {
+	var msg protoMessage
+	for len(data) > 0 {
+		key, n := protoUvarint(data)
+		if n == 0 {
+			return nil, errProtoTruncated
+		}
+		data = data[n:]
+
+		field := protoField{Number: int(key >> 3), Wire: int(key & 7)}
+		switch field.Wire {
+		case protoVarint:
+			field.Value, n = protoUvarint(data)
+			if n == 0 {
+				return nil, errProtoTruncated
+			}
+		case protoFixed64, protoFixed32:
+			n = 8
+			if field.Wire == protoFixed32 {
+				n = 4
+			}
+			if len(data) < n {
+				return nil, errProtoTruncated
+			}
+			for i := n - 1; i >= 0; i-- {
+				field.Value = field.Value<<8 | uint64(data[i])
+			}
+		case protoBytes:
+			size, m := protoUvarint(data)
+			if m == 0 || uint64(len(data)-m) < size {
+				return nil, errProtoTruncated
+			}
+			field.Bytes = data[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d for field %d", field.Wire, field.Number)
+		}
+
+		data = data[n:]
+		msg = append(msg, field)
+	}
+
+	return msg, nil
+}
+
+// protoUvarint reads a varint, returning the number of bytes read or 0 if the data ends before the varint does
+func protoUvarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// last returns the last occurrence of a field, which is the one that counts for non repeated fields
+func (m protoMessage) last(number int) (protoField, bool) {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].Number == number {
+			return m[i], true
+		}
+	}
+	return protoField{}, false
+}
+
+// uint returns a varint field, or 0 if it's missing
+func (m protoMessage) uint(number int) uint64 {
+	field, _ := m.last(number)
+	return field.Value
+}
+
+// bool returns a boolean field, or false if it's missing
+func (m protoMessage) bool(number int) bool {
+	return m.uint(number) != 0
+}
+
+// string returns a string field, or "" if it's missing
+func (m protoMessage) string(number int) string {
+	field, _ := m.last(number)
+	return string(field.Bytes)
+}
+
+// message decodes a nested message field, a missing field is an empty message
+func (m protoMessage) message(number int) (protoMessage, error) {
+	field, ok := m.last(number)
+	if !ok {
+		return nil, nil
+	}
+	return decodeProto(field.Bytes)
+}
+
+// messages decodes every message of a repeated field
+func (m protoMessage) messages(number int) ([]protoMessage, error) {
+	var msgs []protoMessage
+	for _, field := range m {
+		if field.Number != number {
+			continue
+		}
+		msg, err := decodeProto(field.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
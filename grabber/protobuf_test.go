@@ -0,0 +1,105 @@
+package grabber
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// protoBuilder encodes protobuf messages for tests
+type protoBuilder []byte
+
+func (b protoBuilder) key(number int, wire int) protoBuilder {
+	return binary.AppendUvarint(b, uint64(number<<3|wire))
+}
+
+func (b protoBuilder) uint(number int, value uint64) protoBuilder {
+	return binary.AppendUvarint(b.key(number, protoVarint), value)
+}
+
+func (b protoBuilder) bytes(number int, value []byte) protoBuilder {
+	b = binary.AppendUvarint(b.key(number, protoBytes), uint64(len(value)))
+	return append(b, value...)
+}
+
+func (b protoBuilder) string(number int, value string) protoBuilder {
+	return b.bytes(number, []byte(value))
+}
+
+func (b protoBuilder) message(number int, msg protoBuilder) protoBuilder {
+	return b.bytes(number, msg)
+}
+
+func TestDecodeProto(t *testing.T) {
+	fixed := protoBuilder{}.key(4, protoFixed32)
+	fixed = append(fixed, 0x01, 0x02, 0x00, 0x00)
+
+	data := protoBuilder{}.
+		uint(1, 300).
+		string(2, "Kaiju No. 8").
+		message(3, protoBuilder{}.string(1, "first")).
+		message(3, protoBuilder{}.string(1, "second")).
+		uint(5, 1)
+	data = append(data, fixed...)
+
+	msg, err := decodeProto(data)
+	if err != nil {
+		t.Fatalf("decodeProto() error = %v", err)
+	}
+
+	if v := msg.uint(1); v != 300 {
+		t.Errorf("uint(1) = %d, want 300", v)
+	}
+	if v := msg.string(2); v != "Kaiju No. 8" {
+		t.Errorf("string(2) = %q, want %q", v, "Kaiju No. 8")
+	}
+	if !msg.bool(5) || msg.bool(6) {
+		t.Errorf("bool(5), bool(6) = %v, %v, want true, false", msg.bool(5), msg.bool(6))
+	}
+	if v := msg.uint(4); v != 0x0201 {
+		t.Errorf("uint(4) = %#x, want the little endian fixed32 0x201", v)
+	}
+
+	repeated, err := msg.messages(3)
+	if err != nil {
+		t.Fatalf("messages(3) error = %v", err)
+	}
+	if len(repeated) != 2 || repeated[0].string(1) != "first" || repeated[1].string(1) != "second" {
+		t.Errorf("messages(3) = %v, want both messages in order", repeated)
+	}
+
+	last, err := msg.message(3)
+	if err != nil || last.string(1) != "second" {
+		t.Errorf("message(3) = %v, %v, want the last message", last, err)
+	}
+
+	missing, err := msg.message(9)
+	if err != nil || missing != nil || missing.string(1) != "" {
+		t.Errorf("message(9) = %v, %v, want an empty message", missing, err)
+	}
+}
+
+func TestDecodeProto_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated key", data: []byte{0x80}},
+		{name: "truncated varint", data: []byte{0x08, 0xff}},
+		{name: "truncated string", data: protoBuilder{}.string(1, "long string")[:5]},
+		{name: "truncated fixed64", data: []byte{0x09, 0x01, 0x02}},
+		{name: "group wire type", data: []byte{0x0b}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeProto(tt.data); err == nil {
+				t.Errorf("decodeProto(%x) error = nil, want an error", tt.data)
+			}
+		})
+	}
+
+	if _, err := decodeProto([]byte{0x80}); !errors.Is(err, errProtoTruncated) {
+		t.Errorf("decodeProto() error = %v, want errProtoTruncated", err)
+	}
+}
@@ -0,0 +1,134 @@
+package grabber
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Constructor creates a site grabber from a base grabber
+type Constructor func(*Grabber) GrabberInterface
+
+// Matcher reports whether a site can handle the given URL
+type Matcher func(url string) bool
+
+// Site is a registered grabber implementation
+type Site struct {
+	Name  string
+	Match Matcher
+	New   Constructor
+}
+
+// sites holds the registered sites in registration order
+var sites []Site
+
+// loaders register more sites once the registered ones can't handle a URL or site name
+var loaders []func()
+
+// Register adds a site to the registry. Sites are tried in registration order.
+func Register(name string, match Matcher, ctor Constructor) {
+	sites = append(sites, Site{Name: name, Match: match, New: ctor})
+}
+
+// RegisterLoader adds a function registering sites which are costly to set up, like plugins. It only runs, once,
+// when no registered site handles a URL or site name, its sites are tried after the others.
+func RegisterLoader(load func()) {
+	loaders = append(loaders, load)
+}
+
+// runLoaders runs the pending loaders, reporting whether there were any
+func runLoaders() bool {
+	if len(loaders) == 0 {
+		return false
+	}
+
+	pending := loaders
+	loaders = nil
+	for _, load := range pending {
+		load()
+	}
+	return true
+}
+
+// Sites returns the names of all registered sites
+func Sites() []string {
+	names := make([]string, 0, len(sites))
+	for _, s := range sites {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// UnsupportedSiteError is returned when no registered site can handle a URL
+type UnsupportedSiteError struct {
+	URL   string
+	Sites []string
+}
+
+func (e *UnsupportedSiteError) Error() string {
+	return fmt.Sprintf("unsupported site: %s (supported sites: %s)", e.URL, strings.Join(e.Sites, ", "))
+}
+
+// New returns the grabber of the first registered site that matches the URL of g
+func New(g *Grabber) (GrabberInterface, error) {
+	site, errs := newMatching(sites, g)
+	if site == nil {
+		// only the sites of the loaders are left to try
+		registered := len(sites)
+		if runLoaders() {
+			var loadedErrs []error
+			site, loadedErrs = newMatching(sites[registered:], g)
+			errs = append(errs, loadedErrs...)
+		}
+	}
+	if site == nil {
+		unsupported := &UnsupportedSiteError{URL: g.URL, Sites: Sites()}
+		if len(errs) == 0 {
+			return nil, unsupported
+		}
+		// a site failing its test may be the one for the URL, so its error is told along with the supported sites
+		return nil, errors.Join(append(errs, unsupported)...)
+	}
+
+	return site, nil
+}
+
+// newMatching returns the grabber of the first of the sites that matches the URL of g and accepts it, nil when none
+// does. A site failing its test doesn't keep the next ones from being tried, the errors are returned when no site
+// accepts the URL.
+func newMatching(sites []Site, g *Grabber) (GrabberInterface, []error) {
+	var errs []error
+	for _, s := range sites {
+		if !s.Match(g.URL) {
+			continue
+		}
+
+		site := s.New(g)
+		ok, err := site.Test()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error testing site %s: %w", s.Name, err))
+			continue
+		}
+		if ok {
+			return site, nil
+		}
+	}
+
+	return nil, errs
+}
+
+// NewSite returns the grabber of the registered site with the given name (case insensitive)
+func NewSite(name string, g *Grabber) (GrabberInterface, error) {
+	for {
+		for _, s := range sites {
+			if strings.EqualFold(s.Name, name) {
+				return s.New(g), nil
+			}
+		}
+		if !runLoaders() {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("unknown site: %s (supported sites: %s)", name, strings.Join(Sites(), ", "))
+}
@@ -0,0 +1,117 @@
+package grabber
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeSite is a minimal GrabberInterface used to exercise the registry
+type fakeSite struct {
+	*Grabber
+	supported bool
+}
+
+func (f *fakeSite) Test() (bool, error)                       { return f.supported, nil }
+func (f *fakeSite) FetchTitle() (string, error)               { return "Fake", nil }
+func (f *fakeSite) FetchChapters() (Filterables, []error)     { return nil, nil }
+func (f *fakeSite) FetchChapter(Filterable) (*Chapter, error) { return nil, nil }
+
+// withSites replaces the registered sites for the duration of a test, without loaders
+func withSites(t *testing.T, s []Site) {
+	t.Helper()
+	orig, origLoaders := sites, loaders
+	sites, loaders = s, nil
+	t.Cleanup(func() { sites, loaders = orig, origLoaders })
+}
+
+func TestNew_Mangadex(t *testing.T) {
+	g := &Grabber{URL: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"}
+
+	site, err := New(g)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, ok := site.(*Mangadx); !ok {
+		t.Errorf("New() returned %T, want *Mangadx", site)
+	}
+}
+
+func TestNew_UnsupportedSite(t *testing.T) {
+	_, err := New(&Grabber{URL: "https://example.com/manga"})
+	if err == nil {
+		t.Fatal("New() expected error for unsupported site, but got none")
+	}
+
+	var unsupported *UnsupportedSiteError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("New() error = %T, want *UnsupportedSiteError", err)
+	}
+
+	if !strings.Contains(err.Error(), "unsupported site") || !strings.Contains(err.Error(), "MangaDex") {
+		t.Errorf("New() error = %v, want it to list the supported sites", err)
+	}
+}
+
+func TestNew_RegistrationOrder(t *testing.T) {
+	withSites(t, nil)
+
+	matchAll := func(string) bool { return true }
+	Register("Rejecting", matchAll, func(g *Grabber) GrabberInterface {
+		return &fakeSite{Grabber: g, supported: false}
+	})
+	Register("Accepting", matchAll, func(g *Grabber) GrabberInterface {
+		return &fakeSite{Grabber: g, supported: true}
+	})
+
+	site, err := New(&Grabber{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if !site.(*fakeSite).supported {
+		t.Error("New() returned a site whose Test() rejected the URL")
+	}
+
+	if got := strings.Join(Sites(), ","); got != "Rejecting,Accepting" {
+		t.Errorf("Sites() = %v, want Rejecting,Accepting", got)
+	}
+}
+
+func TestNew_Loaders(t *testing.T) {
+	withSites(t, nil)
+
+	Register("Builtin", func(url string) bool { return strings.Contains(url, "builtin") }, func(g *Grabber) GrabberInterface {
+		return &fakeSite{Grabber: g, supported: true}
+	})
+	loads := 0
+	RegisterLoader(func() {
+		loads++
+		Register("Plugin", func(url string) bool { return strings.Contains(url, "plugin") }, func(g *Grabber) GrabberInterface {
+			return &fakeSite{Grabber: g, supported: true}
+		})
+	})
+
+	if _, err := New(&Grabber{URL: "https://builtin.example.com"}); err != nil || loads != 0 {
+		t.Errorf("New() error = %v after %d loads, want the builtin site without loading", err, loads)
+	}
+	if _, err := NewSite("builtin", &Grabber{}); err != nil || loads != 0 {
+		t.Errorf("NewSite() error = %v after %d loads, want the builtin site without loading", err, loads)
+	}
+
+	if _, err := New(&Grabber{URL: "https://plugin.example.com"}); err != nil || loads != 1 {
+		t.Errorf("New() error = %v after %d loads, want the loaded site", err, loads)
+	}
+	if _, err := NewSite("plugin", &Grabber{}); err != nil || loads != 1 {
+		t.Errorf("NewSite() error = %v after %d loads, want the site loaded once", err, loads)
+	}
+
+	var unsupported *UnsupportedSiteError
+	if _, err := New(&Grabber{URL: "https://example.com"}); !errors.As(err, &unsupported) || loads != 1 {
+		t.Errorf("New() error = %v after %d loads, want an UnsupportedSiteError", err, loads)
+	}
+	if _, err := NewSite("missing", &Grabber{}); err == nil || !strings.Contains(err.Error(), "Plugin") {
+		t.Errorf("NewSite() error = %v, want the unknown site reported with the loaded ones", err)
+	}
+}
@@ -0,0 +1,389 @@
+package grabber
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/http"
+)
+
+// scraperMaxPages is the maximum number of pages followed when paginating a chapter list or a chapter
+const scraperMaxPages = 100
+
+// Definition describes how to scrape an HTML site, so sites without an API can be added with a definition file
+// instead of a grabber. Patterns are regular expressions matched against the page HTML.
+type Definition struct {
+	// Name is the name the site is registered under
+	Name string `json:"name"`
+	// Match matches the title URLs of the site
+	Match string `json:"match"`
+	// Title captures the title of the series in its first group
+	Title string `json:"title"`
+	// Language is the language of the chapters of the site
+	Language string `json:"language"`
+	// Chapters describes the chapter list of the title page
+	Chapters DefinitionChapters `json:"chapters"`
+	// Pages describes the chapter pages
+	Pages DefinitionPages `json:"pages"`
+
+	match, title     *regexp.Regexp
+	item, number     *regexp.Regexp
+	chaptersNext     *regexp.Regexp
+	image, pagesNext *regexp.Regexp
+}
+
+// DefinitionChapters describes the chapter list of a title page
+type DefinitionChapters struct {
+	// Item matches each chapter of the list, capturing its link in the "url" named group and optionally its
+	// "number", "title" and "volume"
+	Item string `json:"item"`
+	// Number captures the chapter number in its first group from the chapter title, when Item has no "number" group
+	Number string `json:"number"`
+	// Next captures the link to the next page of the chapter list, for lists spread over several pages
+	Next string `json:"next"`
+}
+
+// DefinitionPages describes the pages of a chapter
+type DefinitionPages struct {
+	// Image captures the link to each page image in its first group
+	Image string `json:"image"`
+	// Next captures the link to the next page of the chapter, for chapters showing one image per page
+	Next string `json:"next"`
+}
+
+// compile compiles the patterns of the definition, checking the required ones are set
+func (d *Definition) compile() (err error) {
+	if d.Name == "" {
+		return errors.New("missing name")
+	}
+
+	patterns := []struct {
+		name     string
+		pattern  string
+		required bool
+		re       **regexp.Regexp
+	}{
+		{"match", d.Match, true, &d.match},
+		{"title", d.Title, true, &d.title},
+		{"chapters.item", d.Chapters.Item, true, &d.item},
+		{"chapters.number", d.Chapters.Number, false, &d.number},
+		{"chapters.next", d.Chapters.Next, false, &d.chaptersNext},
+		{"pages.image", d.Pages.Image, true, &d.image},
+		{"pages.next", d.Pages.Next, false, &d.pagesNext},
+	}
+
+	for _, p := range patterns {
+		if p.pattern == "" {
+			if p.required {
+				return fmt.Errorf("missing %s pattern", p.name)
+			}
+			continue
+		}
+		if *p.re, err = regexp.Compile(p.pattern); err != nil {
+			return fmt.Errorf("invalid %s pattern: %w", p.name, err)
+		}
+	}
+
+	if d.item.SubexpIndex("url") < 0 {
+		return errors.New("chapters.item pattern has no url group")
+	}
+
+	return nil
+}
+
+// ParseDefinition reads a site definition and compiles its patterns
+func ParseDefinition(r io.Reader) (*Definition, error) {
+	d := &Definition{}
+	if err := json.NewDecoder(r).Decode(d); err != nil {
+		return nil, err
+	}
+
+	if err := d.compile(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// LoadDefinitions registers a site for every definition file (*.json) of the directory, a missing directory has no
+// definitions. Invalid files are reported once every valid one was registered.
+func LoadDefinitions(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		d, err := ParseDefinition(f)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid site definition %s: %w", file, err))
+			continue
+		}
+
+		Register(d.Name, d.match.MatchString, func(g *Grabber) GrabberInterface {
+			return NewScraper(d, g)
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+// Scraper is a grabber for HTML sites driven by a Definition
+type Scraper struct {
+	*Grabber
+	def   *Definition
+	title string
+	// body caches the title page, which holds both the title and the first page of the chapter list
+	body string
+}
+
+// NewScraper returns a grabber scraping the site described by the definition
+func NewScraper(def *Definition, g *Grabber) *Scraper {
+	return &Scraper{Grabber: g, def: def}
+}
+
+// ScraperChapter is a chapter of a scraped site
+type ScraperChapter struct {
+	Chapter
+	URL string
+}
+
+// GetId returns the URL of the chapter, which identifies it on the site
+func (c ScraperChapter) GetId() string {
+	return c.URL
+}
+
+// Test checks if the URL belongs to the site of the definition
+func (s *Scraper) Test() (bool, error) {
+	return s.def.match.MatchString(s.URL), nil
+}
+
+// FetchTitle returns the title of the series
+func (s *Scraper) FetchTitle() (string, error) {
+	if s.title != "" {
+		return s.title, nil
+	}
+
+	body, err := s.titlePage()
+	if err != nil {
+		return "", err
+	}
+
+	match := s.def.title.FindStringSubmatch(body)
+	if len(match) < 2 {
+		return "", fmt.Errorf("no title found at %s", s.URL)
+	}
+	s.title = cleanText(match[1])
+
+	return s.title, nil
+}
+
+// FetchChapters returns the chapters of the series, following the chapter list pagination
+func (s *Scraper) FetchChapters() (chapters Filterables, errs []error) {
+	body, err := s.titlePage()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	pageUrl := s.URL
+	seen := map[string]bool{pageUrl: true}
+	for page := 1; ; page++ {
+		for _, match := range s.def.item.FindAllStringSubmatch(body, -1) {
+			chapter, err := s.chapter(pageUrl, match)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			chapters = append(chapters, chapter)
+		}
+
+		next := s.next(s.def.chaptersNext, pageUrl, body)
+		if next == "" || seen[next] || page >= scraperMaxPages {
+			break
+		}
+		seen[next] = true
+
+		pageUrl = next
+		if body, err = fetchPage(pageUrl, s.URL); err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+
+	SortChapters(chapters)
+	return
+}
+
+// chapter returns the chapter of a chapter list match
+func (s *Scraper) chapter(pageUrl string, match []string) (*ScraperChapter, error) {
+	group := func(name string) string {
+		if i := s.def.item.SubexpIndex(name); i >= 0 {
+			return cleanText(match[i])
+		}
+		return ""
+	}
+
+	link, err := resolveUrl(pageUrl, group("url"))
+	if err != nil {
+		return nil, err
+	}
+
+	title := group("title")
+	label := group("number")
+	if label == "" && s.def.number != nil {
+		if m := s.def.number.FindStringSubmatch(title); len(m) > 1 {
+			label = m[1]
+		}
+	}
+	num, _ := strconv.ParseFloat(label, 64)
+	if label == "" {
+		// chapters without a number are told apart by their title
+		label = title
+	}
+
+	return &ScraperChapter{
+		Chapter: Chapter{
+			Number:   num,
+			Label:    label,
+			Volume:   group("volume"),
+			Title:    title,
+			Language: s.def.Language,
+		},
+		URL: link,
+	}, nil
+}
+
+// FetchChapter fetches the pages of a chapter, following the chapter pagination
+func (s *Scraper) FetchChapter(f Filterable) (*Chapter, error) {
+	chap := f.(*ScraperChapter)
+
+	chapter := &Chapter{
+		Title:    chap.Title,
+		Number:   chap.Number,
+		Label:    chap.Label,
+		Volume:   chap.Volume,
+		Language: chap.Language,
+	}
+
+	pageUrl := chap.URL
+	seen := map[string]bool{pageUrl: true}
+	images := map[string]bool{}
+	for page := 1; ; page++ {
+		body, err := fetchPage(pageUrl, s.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range s.def.image.FindAllStringSubmatch(body, -1) {
+			if len(match) < 2 {
+				continue
+			}
+			image, err := resolveUrl(pageUrl, cleanText(match[1]))
+			if err != nil || images[image] {
+				continue
+			}
+			images[image] = true
+			chapter.Pages = append(chapter.Pages, Page{Number: int64(len(chapter.Pages) + 1), URL: image})
+		}
+
+		next := s.next(s.def.pagesNext, pageUrl, body)
+		if next == "" || seen[next] || page >= scraperMaxPages {
+			break
+		}
+		seen[next] = true
+		pageUrl = next
+	}
+
+	if len(chapter.Pages) == 0 {
+		return nil, fmt.Errorf("no page images found at %s", chap.URL)
+	}
+	chapter.PagesCount = int64(len(chapter.Pages))
+
+	return chapter, nil
+}
+
+// titlePage returns the HTML of the title page
+func (s *Scraper) titlePage() (string, error) {
+	if s.body != "" {
+		return s.body, nil
+	}
+
+	body, err := fetchPage(s.URL, "")
+	if err != nil {
+		return "", err
+	}
+	s.body = body
+
+	return body, nil
+}
+
+// next returns the link to the next page captured by the pattern, or an empty string when there is none
+func (s *Scraper) next(re *regexp.Regexp, pageUrl string, body string) string {
+	if re == nil {
+		return ""
+	}
+
+	match := re.FindStringSubmatch(body)
+	if len(match) < 2 {
+		return ""
+	}
+
+	next, err := resolveUrl(pageUrl, cleanText(match[1]))
+	if err != nil {
+		return ""
+	}
+	return next
+}
+
+// fetchPage returns the HTML of the page
+func fetchPage(pageUrl string, referer string) (string, error) {
+	rbody, err := http.Get(http.RequestParams{URL: pageUrl, Referer: referer})
+	if err != nil {
+		return "", err
+	}
+	defer rbody.Close()
+
+	body, err := io.ReadAll(rbody)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// resolveUrl resolves a link found in a page against the URL of the page
+func resolveUrl(pageUrl string, link string) (string, error) {
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// cleanText unescapes the HTML entities of a captured text and trims its spaces
+func cleanText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(s))
+}
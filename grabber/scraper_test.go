@@ -0,0 +1,167 @@
+package grabber
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scraperServer serves the HTML fixtures of the example site definition
+func scraperServer(t *testing.T) (*httptest.Server, *Definition) {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", "scraper", "example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	def, err := ParseDefinition(f)
+	if err != nil {
+		t.Fatalf("ParseDefinition() error = %v", err)
+	}
+
+	ts := httptest.NewServer(http.FileServer(http.Dir(filepath.Join("testdata", "scraper"))))
+	t.Cleanup(ts.Close)
+
+	return ts, def
+}
+
+func TestScraper_FetchTitle(t *testing.T) {
+	ts, def := scraperServer(t)
+
+	s := NewScraper(def, &Grabber{URL: ts.URL + "/series/one-piece.html"})
+	if ok, _ := s.Test(); !ok {
+		t.Fatal("Test() = false for a title URL of the site")
+	}
+
+	title, err := s.FetchTitle()
+	if err != nil {
+		t.Fatalf("FetchTitle() error = %v", err)
+	}
+	if title != "One Piece & Friends" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "One Piece & Friends")
+	}
+}
+
+func TestScraper_FetchChapters(t *testing.T) {
+	ts, def := scraperServer(t)
+
+	s := NewScraper(def, &Grabber{URL: ts.URL + "/series/one-piece.html"})
+	chapters, errs := s.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+
+	var labels, volumes []string
+	for _, c := range chapters {
+		labels = append(labels, c.GetLabel())
+		volumes = append(volumes, c.GetVolume())
+	}
+
+	// both pages of the list are read, the link back to the first page is not followed again
+	if !reflect.DeepEqual(labels, []string{"1", "2", "3", "Color Spread"}) {
+		t.Errorf("chapter labels = %v, want [1 2 3 Color Spread]", labels)
+	}
+	if !reflect.DeepEqual(volumes, []string{"1", "1", "2", ""}) {
+		t.Errorf("chapter volumes = %v, want [1 1 2 ]", volumes)
+	}
+
+	first := chapters[0].(*ScraperChapter)
+	if first.URL != ts.URL+"/read/one-piece/1.html" || first.Title != "Chapter 1: Romance Dawn" || first.Language != "en" {
+		t.Errorf("first chapter = %+v, want chapter 1 with its absolute URL", first)
+	}
+}
+
+func TestScraper_FetchChapter(t *testing.T) {
+	ts, def := scraperServer(t)
+
+	s := NewScraper(def, &Grabber{URL: ts.URL + "/series/one-piece.html"})
+	chapter, err := s.FetchChapter(&ScraperChapter{
+		Chapter: Chapter{Number: 1, Label: "1", Title: "Chapter 1: Romance Dawn"},
+		URL:     ts.URL + "/read/one-piece/1.html",
+	})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	var urls []string
+	for _, page := range chapter.Pages {
+		urls = append(urls, page.URL)
+	}
+	expected := []string{
+		ts.URL + "/images/one-piece/1/01.jpg",
+		ts.URL + "/images/one-piece/1/02.jpg",
+		"https://cdn.example.com/one-piece/1/03.jpg?w=800&q=90",
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("page URLs = %v, want %v", urls, expected)
+	}
+	if chapter.PagesCount != 3 || chapter.Pages[2].Number != 3 || chapter.Label != "1" {
+		t.Errorf("chapter = %+v, want 3 numbered pages", chapter)
+	}
+
+	_, err = s.FetchChapter(&ScraperChapter{URL: ts.URL + "/read/one-piece/2.html"})
+	if err == nil || !strings.Contains(err.Error(), "no page images") {
+		t.Errorf("FetchChapter() error = %v, want an error for a chapter without images", err)
+	}
+}
+
+func TestParseDefinition_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		expected   string
+	}{
+		{name: "missing name", definition: `{"match": "x"}`, expected: "missing name"},
+		{name: "missing pattern", definition: `{"name": "x", "match": "x", "title": "x"}`, expected: "missing chapters.item"},
+		{name: "invalid pattern", definition: `{"name": "x", "match": "(", "title": "x"}`, expected: "invalid match"},
+		{name: "no url group", definition: `{"name": "x", "match": "x", "title": "x", "chapters": {"item": "x"}, "pages": {"image": "x"}}`, expected: "no url group"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDefinition(strings.NewReader(tt.definition))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("ParseDefinition() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	withSites(t, nil)
+
+	dir := t.TempDir()
+	example, err := os.ReadFile(filepath.Join("testdata", "scraper", "example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "example.json"), example, 0644)
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"name": "Broken"}`), 0644)
+
+	err = LoadDefinitions(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("LoadDefinitions() error = %v, want the broken definition reported", err)
+	}
+
+	if sites := Sites(); !reflect.DeepEqual(sites, []string{"Example Scans"}) {
+		t.Fatalf("Sites() = %v, want the valid definition registered", sites)
+	}
+
+	site, err := New(&Grabber{URL: "http://127.0.0.1:8080/series/one-piece.html"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, ok := site.(*Scraper); !ok {
+		t.Errorf("New() returned %T, want *Scraper", site)
+	}
+
+	if err = LoadDefinitions(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("LoadDefinitions() error = %v for a missing directory", err)
+	}
+}
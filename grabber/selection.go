@@ -0,0 +1,186 @@
+package grabber
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IsNumbered reports whether the chapter is identified by its number rather than by a label like "Extra"
+func IsNumbered(f Filterable) bool {
+	label := strings.TrimSpace(f.GetLabel())
+	if label == "" {
+		return true
+	}
+	_, err := strconv.ParseFloat(label, 64)
+	return err == nil
+}
+
+// ChapterName returns the number of the chapter, or its label when it isn't numbered
+func ChapterName(f Filterable) string {
+	if IsNumbered(f) {
+		return strconv.FormatFloat(f.GetNumber(), 'f', -1, 64)
+	}
+	return strings.TrimSpace(f.GetLabel())
+}
+
+// ChapterKey identifies a chapter whatever its release. Numbered chapters are identified by their number, labelled
+// ones by their label and volume, as volumes often have their own "Extra" chapter.
+func ChapterKey(f Filterable) string {
+	if IsNumbered(f) {
+		return strconv.FormatFloat(f.GetNumber(), 'f', -1, 64)
+	}
+
+	key := strings.ToLower(strings.TrimSpace(f.GetLabel()))
+	if f.GetVolume() != "" {
+		key += fmt.Sprintf(" (vol %s)", f.GetVolume())
+	}
+	return key
+}
+
+// ChapterLess reports whether chapter a comes before chapter b: numbered chapters come first by number, followed
+// by labelled chapters ordered by volume and label
+func ChapterLess(a, b Filterable) bool {
+	na, nb := IsNumbered(a), IsNumbered(b)
+	if na != nb {
+		return na
+	}
+	if na {
+		return a.GetNumber() < b.GetNumber()
+	}
+
+	if va, vb := volumeOrder(a.GetVolume()), volumeOrder(b.GetVolume()); va != vb {
+		return va < vb
+	}
+	return strings.ToLower(a.GetLabel()) < strings.ToLower(b.GetLabel())
+}
+
+// volumeOrder returns a sortable value for a volume, volumes that aren't numbers go last
+func volumeOrder(volume string) float64 {
+	num, err := strconv.ParseFloat(volume, 64)
+	if err != nil {
+		return math.MaxFloat64
+	}
+	return num
+}
+
+// SortChapters sorts the chapters in reading order
+func SortChapters(chapters Filterables) {
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return ChapterLess(chapters[i], chapters[j])
+	})
+}
+
+// IsBlocked reports whether the chapter was released by one of the blocked groups
+func (s Settings) IsBlocked(f Filterable) bool {
+	for _, group := range f.GetGroups() {
+		for _, blocked := range s.BlockedGroups {
+			if strings.EqualFold(group, blocked) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupRank returns the position of the most preferred group of the chapter, or the number of preferred groups
+// when none of the chapter groups is preferred
+func (s Settings) groupRank(f Filterable) int {
+	rank := len(s.PreferredGroups)
+	for _, group := range f.GetGroups() {
+		for i, preferred := range s.PreferredGroups {
+			if i < rank && strings.EqualFold(group, preferred) {
+				rank = i
+			}
+		}
+	}
+	return rank
+}
+
+// languageRank returns the priority of the chapter language, or the number of requested languages when the
+// language was not requested
+func (s Settings) languageRank(f Filterable) int {
+	langs := s.LanguagePriority()
+	for i, lang := range langs {
+		if strings.EqualFold(f.GetLanguage(), lang) {
+			return i
+		}
+	}
+	return len(langs)
+}
+
+// better reports whether chapter a should be picked over chapter b
+func (s Settings) better(a, b Filterable) bool {
+	// a release that can be downloaded always beats one hosted elsewhere
+	if da, db := IsDownloadable(a), IsDownloadable(b); da != db {
+		return da
+	}
+
+	if ra, rb := s.languageRank(a), s.languageRank(b); ra != rb {
+		return ra < rb
+	}
+
+	if ra, rb := s.groupRank(a), s.groupRank(b); ra != rb {
+		return ra < rb
+	}
+
+	// break ties by group name so the same release is picked whatever the feed order
+	return strings.ToLower(strings.Join(a.GetGroups(), ",")) < strings.ToLower(strings.Join(b.GetGroups(), ","))
+}
+
+// Dedupe keeps a single release per chapter, as identified by ChapterKey: releases by blocked groups are dropped and, among the remaining
+// ones, downloadable releases win, then the release in the highest priority language, then the release of the most
+// preferred group. Chapters are returned in the order they first appear.
+func Dedupe(chapters Filterables, s Settings) Filterables {
+	best := make(map[string]Filterable)
+	var order []string
+
+	for _, ch := range chapters {
+		if s.IsBlocked(ch) {
+			continue
+		}
+
+		key := ChapterKey(ch)
+		current, seen := best[key]
+		if !seen {
+			order = append(order, key)
+			best[key] = ch
+		} else if s.better(ch, current) {
+			best[key] = ch
+		}
+	}
+
+	deduped := make(Filterables, 0, len(order))
+	for _, key := range order {
+		deduped = append(deduped, best[key])
+	}
+
+	return deduped
+}
+
+// GroupsByChapter returns the sorted, unique group names releasing each chapter, by ChapterKey
+func GroupsByChapter(chapters Filterables) map[string][]string {
+	groups := make(map[string][]string)
+	seen := make(map[string]map[string]bool)
+
+	for _, ch := range chapters {
+		key := ChapterKey(ch)
+		if seen[key] == nil {
+			seen[key] = make(map[string]bool)
+		}
+		for _, group := range ch.GetGroups() {
+			if !seen[key][group] {
+				seen[key][group] = true
+				groups[key] = append(groups[key], group)
+			}
+		}
+	}
+
+	for _, g := range groups {
+		sort.Strings(g)
+	}
+
+	return groups
+}
@@ -0,0 +1,220 @@
+package grabber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func groupChapters() Filterables {
+	return Filterables{
+		&Chapter{Number: 1, Title: "a", Groups: []string{"Zeta Scans"}},
+		&Chapter{Number: 1, Title: "b", Groups: []string{"Alpha Scans"}},
+		&Chapter{Number: 2, Title: "c", Groups: []string{"Bad Group"}},
+		&Chapter{Number: 2, Title: "d", Groups: []string{"Zeta Scans", "Joint Group"}},
+		&Chapter{Number: 3, Title: "e", Groups: []string{"bad group"}},
+	}
+}
+
+func titles(chapters Filterables) []string {
+	var t []string
+	for _, ch := range chapters {
+		t = append(t, ch.GetTitle())
+	}
+	return t
+}
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		expected []string
+	}{
+		{
+			name:     "no preferences picks by group name",
+			settings: Settings{},
+			expected: []string{"b", "c", "e"},
+		},
+		{
+			name:     "preferred group wins",
+			settings: Settings{PreferredGroups: []string{"zeta scans"}},
+			expected: []string{"a", "d", "e"},
+		},
+		{
+			name:     "preference order is respected",
+			settings: Settings{PreferredGroups: []string{"Joint Group", "Alpha Scans"}},
+			expected: []string{"b", "d", "e"},
+		},
+		{
+			name:     "blocked groups are dropped case insensitively",
+			settings: Settings{BlockedGroups: []string{"Bad Group"}},
+			expected: []string{"b", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := titles(Dedupe(groupChapters(), tt.settings))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Dedupe() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDedupe_Deterministic(t *testing.T) {
+	chapters := groupChapters()
+	reversed := make(Filterables, len(chapters))
+	for i, ch := range chapters {
+		reversed[len(chapters)-1-i] = ch
+	}
+
+	settings := Settings{PreferredGroups: []string{"Alpha Scans"}}
+	a := Dedupe(chapters, settings)
+	b := Dedupe(reversed, settings)
+
+	picked := make(map[float64]string)
+	for _, ch := range a {
+		picked[ch.GetNumber()] = ch.GetTitle()
+	}
+	for _, ch := range b {
+		if picked[ch.GetNumber()] != ch.GetTitle() {
+			t.Errorf("Dedupe() picked %s for chapter %v depending on feed order, want %s", ch.GetTitle(), ch.GetNumber(), picked[ch.GetNumber()])
+		}
+	}
+}
+
+func TestSettings_IsBlocked(t *testing.T) {
+	s := Settings{BlockedGroups: []string{"Bad Group"}}
+
+	if !s.IsBlocked(&Chapter{Groups: []string{"Good Group", "BAD GROUP"}}) {
+		t.Error("IsBlocked() = false for chapter with a blocked group")
+	}
+
+	if s.IsBlocked(&Chapter{Groups: []string{"Good Group"}}) {
+		t.Error("IsBlocked() = true for chapter without blocked groups")
+	}
+
+	if s.IsBlocked(&Chapter{}) {
+		t.Error("IsBlocked() = true for chapter without groups")
+	}
+}
+
+func TestGroupsByChapter(t *testing.T) {
+	groups := GroupsByChapter(groupChapters())
+
+	expected := map[string][]string{
+		"1": {"Alpha Scans", "Zeta Scans"},
+		"2": {"Bad Group", "Joint Group", "Zeta Scans"},
+		"3": {"bad group"},
+	}
+
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("GroupsByChapter() = %v, want %v", groups, expected)
+	}
+}
+
+func TestDedupe_LanguagePriority(t *testing.T) {
+	chapters := Filterables{
+		&Chapter{Number: 1, Title: "en-1", Language: "en", Groups: []string{"Preferred"}},
+		&Chapter{Number: 1, Title: "es-1", Language: "es"},
+		&Chapter{Number: 2, Title: "en-2", Language: "en"},
+		&Chapter{Number: 3, Title: "fr-3", Language: "fr"},
+		&Chapter{Number: 3, Title: "es-3", Language: "es"},
+	}
+
+	settings := Settings{Languages: []string{"es", "en"}, PreferredGroups: []string{"Preferred"}}
+	result := titles(Dedupe(chapters, settings))
+
+	expected := []string{"es-1", "en-2", "es-3"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Dedupe() = %v, want %v", result, expected)
+	}
+}
+
+func TestSettings_LanguagePriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		expected []string
+	}{
+		{name: "no language", settings: Settings{}, expected: nil},
+		{name: "single language", settings: Settings{Language: "en"}, expected: []string{"en"}},
+		{name: "languages take precedence", settings: Settings{Language: "en", Languages: []string{"es", "en"}}, expected: []string{"es", "en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.settings.LanguagePriority(); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("LanguagePriority() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDedupe_PrefersDownloadable(t *testing.T) {
+	chapters := Filterables{
+		&MangadxChapter{Chapter: Chapter{Number: 1, Title: "official", Language: "en"}, ExternalUrl: "https://official.example.com/1"},
+		&MangadxChapter{Chapter: Chapter{Number: 1, Title: "scan", Language: "es"}},
+		&MangadxChapter{Chapter: Chapter{Number: 2, Title: "only official", Language: "en"}, Unavailable: true},
+	}
+
+	result := titles(Dedupe(chapters, Settings{Languages: []string{"en", "es"}}))
+	if !reflect.DeepEqual(result, []string{"scan", "only official"}) {
+		t.Errorf("Dedupe() = %v, want the downloadable release and the only release of chapter 2", result)
+	}
+}
+
+func TestChapterKey(t *testing.T) {
+	tests := []struct {
+		chapter Filterable
+		key     string
+		name    string
+	}{
+		{chapter: &Chapter{Number: 3}, key: "3", name: "3"},
+		{chapter: &Chapter{Number: 10.5, Label: "10.50"}, key: "10.5", name: "10.5"},
+		{chapter: &Chapter{Label: "Extra"}, key: "extra", name: "Extra"},
+		{chapter: &Chapter{Label: "Extra", Volume: "2"}, key: "extra (vol 2)", name: "Extra"},
+		{chapter: &Chapter{Label: "Oneshot"}, key: "oneshot", name: "Oneshot"},
+	}
+
+	for _, tt := range tests {
+		if key := ChapterKey(tt.chapter); key != tt.key {
+			t.Errorf("ChapterKey(%+v) = %q, want %q", tt.chapter, key, tt.key)
+		}
+		if name := ChapterName(tt.chapter); name != tt.name {
+			t.Errorf("ChapterName(%+v) = %q, want %q", tt.chapter, name, tt.name)
+		}
+	}
+}
+
+func TestSortChapters(t *testing.T) {
+	chapters := Filterables{
+		&Chapter{Label: "Extra", Volume: "2", Title: "extra-2"},
+		&Chapter{Number: 2, Label: "2", Title: "2"},
+		&Chapter{Label: "Oneshot", Title: "oneshot"},
+		&Chapter{Label: "Extra", Volume: "1", Title: "extra-1"},
+		&Chapter{Number: 1, Label: "1", Title: "1"},
+	}
+
+	SortChapters(chapters)
+
+	expected := []string{"1", "2", "extra-1", "extra-2", "oneshot"}
+	if result := titles(chapters); !reflect.DeepEqual(result, expected) {
+		t.Errorf("SortChapters() = %v, want %v", result, expected)
+	}
+}
+
+func TestDedupe_Labels(t *testing.T) {
+	chapters := Filterables{
+		&Chapter{Label: "Oneshot", Title: "oneshot"},
+		&Chapter{Number: 0, Label: "0", Title: "prologue"},
+		&Chapter{Label: "Extra", Volume: "1", Title: "extra-1"},
+		&Chapter{Label: "Extra", Volume: "2", Title: "extra-2"},
+		&Chapter{Label: "extra", Volume: "2", Title: "extra-2 again", Groups: []string{"Other"}},
+	}
+
+	expected := []string{"oneshot", "prologue", "extra-1", "extra-2"}
+	if result := titles(Dedupe(chapters, Settings{})); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Dedupe() = %v, want %v", result, expected)
+	}
+}
@@ -1,31 +1,134 @@
 package grabber
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Settings holds configuration for the grabber
 type Settings struct {
 	Language string
-	Bundle   bool
+	// Languages lists translation languages by priority, when set it takes precedence over Language
+	Languages []string
+	Bundle    bool
+	// PreferredGroups lists scanlation groups by preference, used to pick between duplicate chapters
+	PreferredGroups []string
+	// BlockedGroups lists scanlation groups whose releases are never selected
+	BlockedGroups []string
+	// DataSaver requests compressed images, when the site offers them
+	DataSaver bool
+	// ContentRatings lists the content ratings of the titles shown, DefaultContentRatings is used when empty
+	ContentRatings []string
+	// Auth authenticates requests to the site API, requests are anonymous when nil
+	Auth TokenSource
+}
+
+// TokenSource hands out access tokens for an authenticated session
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// ContentRatings are the known content ratings, from the safest
+var ContentRatings = []string{"safe", "suggestive", "erotica", "pornographic"}
+
+// DefaultContentRatings are the content ratings shown when none are requested
+var DefaultContentRatings = []string{"safe", "suggestive", "erotica"}
+
+// ContentRatingFilter returns the content ratings of the titles to show
+func (s Settings) ContentRatingFilter() []string {
+	if len(s.ContentRatings) > 0 {
+		return s.ContentRatings
+	}
+	return DefaultContentRatings
+}
+
+// AllowsContentRating reports whether titles with the given content rating are shown, titles without a rating
+// always are
+func (s Settings) AllowsContentRating(rating string) bool {
+	if rating == "" {
+		return true
+	}
+	for _, allowed := range s.ContentRatingFilter() {
+		if strings.EqualFold(rating, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// LanguagePriority returns the requested translation languages, highest priority first
+func (s Settings) LanguagePriority() []string {
+	if len(s.Languages) > 0 {
+		return s.Languages
+	}
+	if s.Language != "" {
+		return []string{s.Language}
+	}
+	return nil
+}
+
+// NoChaptersError is returned when a title has no chapters in any of the requested languages
+type NoChaptersError struct {
+	Languages []string
+	Available []string
+}
+
+func (e *NoChaptersError) Error() string {
+	if len(e.Available) == 0 {
+		return fmt.Sprintf("no chapters available in %s", strings.Join(e.Languages, ", "))
+	}
+	return fmt.Sprintf("no chapters available in %s; this title is translated to: %s",
+		strings.Join(e.Languages, ", "), strings.Join(e.Available, ", "))
 }
 
 // Page represents a single manga page
 type Page struct {
 	Number int64
 	URL    string
+	// Referer is sent with the image request, for image servers refusing requests from other sites
+	Referer string
+	// EncryptionKey is the hex encoded key the image is XOR encrypted with, images are not encrypted when empty
+	EncryptionKey string
 }
 
 // Chapter represents a manga chapter
 type Chapter struct {
-	Number     float64
+	Number float64
+	// Label is the chapter number as given by the site, like "12.5", "Extra" or "Oneshot". Chapters without a label
+	// are identified by their number.
+	Label      string
+	Volume     string
 	Title      string
 	Language   string
+	Groups     []string
 	PagesCount int64
 	Pages      []Page
+	// Quality describes the image quality of the pages, if the site offers more than one
+	Quality string
+	// LongStrip is set for vertical comics, like webtoons, whose pages are slices of a single long strip
+	LongStrip bool
+	// id identifies the chapter on its site so its pages can be refreshed
+	id string
 }
 
+// Image qualities a chapter can be downloaded in
+const (
+	QualityOriginal  = "original"
+	QualityDataSaver = "data-saver"
+)
+
 // Filterable interface for objects that can be filtered by number
 type Filterable interface {
 	GetNumber() float64
+	GetLabel() string
+	GetVolume() string
 	GetLanguage() string
 	GetTitle() string
+	GetGroups() []string
+	// GetId returns the identifier of the chapter on its site, if it has one
+	GetId() string
 }
 
 // Filterables is a slice of Filterable objects
@@ -36,6 +139,16 @@ func (c Chapter) GetNumber() float64 {
 	return c.Number
 }
 
+// GetLabel implements Filterable for Chapter
+func (c Chapter) GetLabel() string {
+	return c.Label
+}
+
+// GetVolume implements Filterable for Chapter
+func (c Chapter) GetVolume() string {
+	return c.Volume
+}
+
 // GetLanguage implements Filterable for Chapter
 func (c Chapter) GetLanguage() string {
 	return c.Language
@@ -46,6 +159,16 @@ func (c Chapter) GetTitle() string {
 	return c.Title
 }
 
+// GetGroups implements Filterable for Chapter
+func (c Chapter) GetGroups() []string {
+	return c.Groups
+}
+
+// GetId implements Filterable for Chapter
+func (c Chapter) GetId() string {
+	return c.id
+}
+
 // Grabber is the base grabber struct
 type Grabber struct {
 	URL      string
@@ -66,3 +189,203 @@ type GrabberInterface interface {
 	FetchChapters() (Filterables, []error)
 	FetchChapter(Filterable) (*Chapter, error)
 }
+
+// UnavailableChapter is implemented by chapters which may not be downloadable from the site, like officially
+// licensed chapters hosted elsewhere
+type UnavailableChapter interface {
+	// IsUnavailable reports whether the pages of the chapter can't be downloaded
+	IsUnavailable() bool
+	// ExternalLink returns the URL of the official release, if the chapter is hosted elsewhere
+	ExternalLink() string
+}
+
+// IsDownloadable reports whether the pages of the chapter can be downloaded from the site
+func IsDownloadable(f Filterable) bool {
+	u, ok := f.(UnavailableChapter)
+	return !ok || !u.IsUnavailable()
+}
+
+// SearchResult represents a title found by a site search
+type SearchResult struct {
+	Title     string
+	Id        string
+	URL       string
+	Year      int
+	Status    string
+	Languages []string
+}
+
+// Searcher is implemented by grabbers that can search titles by name
+type Searcher interface {
+	Search(query string) ([]SearchResult, error)
+}
+
+// TitleList is a list of titles curated on a site, like a MangaDex custom list
+type TitleList struct {
+	Name   string
+	Titles []SearchResult
+}
+
+// ListFetcher is implemented by grabbers able to fetch the titles of a ListURL
+type ListFetcher interface {
+	FetchList() (*TitleList, error)
+}
+
+// URLKind tells what a site URL points to
+type URLKind int
+
+// Kinds of site URLs
+const (
+	TitleURL URLKind = iota
+	ChapterURL
+	ListURL
+	GroupURL
+)
+
+func (k URLKind) String() string {
+	switch k {
+	case ChapterURL:
+		return "chapter"
+	case ListURL:
+		return "list"
+	case GroupURL:
+		return "group"
+	}
+	return "title"
+}
+
+// Classifier is implemented by grabbers whose URLs may point to something else than a title, grabbers without it
+// only handle TitleURL
+type Classifier interface {
+	Classify() URLKind
+}
+
+// ChapterResolver is implemented by grabbers able to resolve a ChapterURL to its title
+type ChapterResolver interface {
+	// ResolveChapter returns the URL of the title of the chapter along with the chapter
+	ResolveChapter() (string, Filterable, error)
+}
+
+// ImageReport describes the outcome of a single image download
+type ImageReport struct {
+	URL      string
+	Success  bool
+	Bytes    int
+	Duration time.Duration
+	Cached   bool
+}
+
+// ContentRatingError is returned when a title is excluded by the content rating filter
+type ContentRatingError struct {
+	Rating  string
+	Allowed []string
+}
+
+func (e *ContentRatingError) Error() string {
+	return fmt.Sprintf("this title is rated %s, which is excluded by the content rating filter (%s)",
+		e.Rating, strings.Join(e.Allowed, ", "))
+}
+
+// IncompleteFeedError is returned when a site can't list every chapter of a title
+type IncompleteFeedError struct {
+	Fetched int
+	Total   int
+}
+
+func (e *IncompleteFeedError) Error() string {
+	return fmt.Sprintf("only %d of %d chapters could be listed", e.Fetched, e.Total)
+}
+
+// ErrLoginRequired is returned by requests that need an authenticated session when the user is not logged in
+var ErrLoginRequired = errors.New("this requires logging in, run mango login first")
+
+// FollowedSeries is a series the logged in user follows along with its new chapters
+type FollowedSeries struct {
+	Title    string
+	URL      string
+	Chapters Filterables
+}
+
+// FollowsFetcher is implemented by grabbers able to list the new chapters of the series the logged in user follows
+type FollowsFetcher interface {
+	FetchFollowedChapters(since time.Time) ([]FollowedSeries, error)
+}
+
+// ReadMarker is implemented by grabbers able to sync read markers with the account of the logged in user
+type ReadMarker interface {
+	// ReadChapters returns the chapters the user read among the given ones
+	ReadChapters(chapters Filterables) (Filterables, error)
+	// MarkRead marks the chapters read
+	MarkRead(chapters Filterables) error
+}
+
+// SeriesMetadata describes a series beyond its title
+type SeriesMetadata struct {
+	Title            string
+	Description      string
+	Authors          []string
+	Artists          []string
+	Genres           []string
+	Tags             []string
+	Status           string
+	Year             int
+	Demographic      string
+	OriginalLanguage string
+	ContentRating    string
+	// Links maps the name of an external site to the URL of the series on it
+	Links map[string]string
+}
+
+// MetadataFetcher is implemented by grabbers able to fetch the metadata of a series
+type MetadataFetcher interface {
+	FetchMetadata() (*SeriesMetadata, error)
+}
+
+// Cover is a cover image of a series, Volume is empty for the main series cover
+type Cover struct {
+	Volume string
+	URL    string
+}
+
+// CoverFetcher is implemented by grabbers able to fetch the cover art of a series
+type CoverFetcher interface {
+	FetchCovers() ([]Cover, error)
+}
+
+// CoverFor returns the cover of the given volume, falling back to the series cover
+func CoverFor(covers []Cover, volume string) (Cover, bool) {
+	var series *Cover
+	for i, c := range covers {
+		if volume != "" && c.Volume == volume {
+			return c, true
+		}
+		if c.Volume == "" && series == nil {
+			series = &covers[i]
+		}
+	}
+
+	if series == nil {
+		return Cover{}, false
+	}
+
+	return *series, true
+}
+
+// ErrNoImageSources is returned by a Refresher when there are no more image sources to try
+var ErrNoImageSources = errors.New("no more image sources to try")
+
+// Refresher is implemented by grabbers able to serve the pages of a chapter from other image sources. Each attempt
+// may return a different source, ErrNoImageSources is returned once every source was tried.
+type Refresher interface {
+	RefreshChapter(chapter *Chapter, attempt int) (*Chapter, error)
+}
+
+// PageFetcher is implemented by sources whose pages are not downloaded over HTTP, like files already on disk
+type PageFetcher interface {
+	FetchPage(Page) ([]byte, error)
+}
+
+// Reporter is implemented by grabbers whose image servers want to be told how each download went
+type Reporter interface {
+	Report(ImageReport) error
+}
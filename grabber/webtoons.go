@@ -0,0 +1,237 @@
+package grabber
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// Webtoons is a grabber for webtoons.com
+type Webtoons struct {
+	*Grabber
+	title string
+	// body is the first page of the episode list, which also holds the title
+	body string
+}
+
+func init() {
+	Register("Webtoons", webtoonsUrlRe.MatchString, func(g *Grabber) GrabberInterface {
+		return NewWebtoons(g)
+	})
+}
+
+// webtoonsUrlRe matches webtoons episode list URLs
+var webtoonsUrlRe = regexp.MustCompile(`webtoons\.com/.+/list\?.*title_no=\d+`)
+
+// webtoonsMaxListPages is the maximum number of episode list pages read, lists show 10 episodes per page
+const webtoonsMaxListPages = 500
+
+var (
+	webtoonsTitleRe    = regexp.MustCompile(`<meta property="og:title" content="([^"]+)"`)
+	webtoonsEpisodeRe  = regexp.MustCompile(`(?s)<li class="_episodeItem"[^>]*data-episode-no="(\d+)"[^>]*>(.*?)</li>`)
+	webtoonsLinkRe     = regexp.MustCompile(`<a href="([^"]+)"`)
+	webtoonsSubjectRe  = regexp.MustCompile(`(?s)<span class="subj"><span>(.*?)</span>`)
+	webtoonsImageTagRe = regexp.MustCompile(`<img[^>]*class="_images[^"]*"[^>]*>`)
+	webtoonsImageUrlRe = regexp.MustCompile(`data-url="([^"]+)"`)
+	webtoonsLanguageRe = regexp.MustCompile(`webtoons\.com/([a-z]{2}(?:-[a-z]+)?)/`)
+	webtoonsTagsRe     = regexp.MustCompile(`<[^>]+>`)
+)
+
+func NewWebtoons(g *Grabber) *Webtoons {
+	return &Webtoons{Grabber: g}
+}
+
+// WebtoonsChapter represents a Webtoons episode
+type WebtoonsChapter struct {
+	Chapter
+	URL string
+}
+
+// GetId returns the URL of the episode, which identifies it on the site
+func (c WebtoonsChapter) GetId() string {
+	return c.URL
+}
+
+// Test checks if the URL is a Webtoons episode list
+func (w *Webtoons) Test() (bool, error) {
+	return webtoonsUrlRe.MatchString(w.URL), nil
+}
+
+// FetchTitle returns the title of the webtoon
+func (w *Webtoons) FetchTitle() (string, error) {
+	if w.title != "" {
+		return w.title, nil
+	}
+
+	body, err := w.firstPage()
+	if err != nil {
+		return "", err
+	}
+
+	match := webtoonsTitleRe.FindStringSubmatch(body)
+	if match == nil {
+		return "", fmt.Errorf("no title found at %s", w.URL)
+	}
+	w.title = cleanText(match[1])
+
+	return w.title, nil
+}
+
+// FetchChapters returns the episodes of the webtoon, reading the episode list page by page
+func (w *Webtoons) FetchChapters() (chapters Filterables, errs []error) {
+	seen := make(map[string]bool)
+	for page := 1; page <= webtoonsMaxListPages; page++ {
+		body, err := w.firstPage()
+		if page > 1 {
+			body, err = fetchPage(w.listPage(page), w.URL)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		// pages past the last one show the last episodes again
+		added := 0
+		for _, match := range webtoonsEpisodeRe.FindAllStringSubmatch(body, -1) {
+			if seen[match[1]] {
+				continue
+			}
+			seen[match[1]] = true
+
+			chapter, err := w.episode(match[1], match[2])
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			chapters = append(chapters, chapter)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+	}
+
+	SortChapters(chapters)
+	return
+}
+
+// listPage returns the URL of a page of the episode list
+func (w *Webtoons) listPage(page int) string {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return w.URL
+	}
+
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// firstPage returns the HTML of the first page of the episode list, it's requested once and shared by FetchTitle and
+// FetchChapters
+func (w *Webtoons) firstPage() (string, error) {
+	if w.body != "" {
+		return w.body, nil
+	}
+
+	body, err := fetchPage(w.listPage(1), w.URL)
+	if err != nil {
+		return "", err
+	}
+	w.body = body
+
+	return body, nil
+}
+
+// episode returns the chapter of an episode list item
+func (w *Webtoons) episode(number string, item string) (*WebtoonsChapter, error) {
+	link := webtoonsLinkRe.FindStringSubmatch(item)
+	if link == nil {
+		return nil, fmt.Errorf("episode %s has no link", number)
+	}
+
+	// viewer links are absolute, they are resolved against the list URL so the episode is read from the same host
+	viewer, err := url.Parse(html.UnescapeString(link[1]))
+	if err != nil {
+		return nil, err
+	}
+	episodeUrl, err := resolveUrl(w.URL, viewer.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+
+	title := ""
+	if subject := webtoonsSubjectRe.FindStringSubmatch(item); subject != nil {
+		title = cleanText(webtoonsTagsRe.ReplaceAllString(subject[1], ""))
+	}
+
+	num, _ := strconv.ParseFloat(number, 64)
+
+	return &WebtoonsChapter{
+		Chapter: Chapter{
+			Number:    num,
+			Label:     number,
+			Title:     title,
+			Language:  w.language(),
+			LongStrip: true,
+		},
+		URL: episodeUrl,
+	}, nil
+}
+
+// language returns the language of the webtoon, which is part of its URL
+func (w *Webtoons) language() string {
+	if match := webtoonsLanguageRe.FindStringSubmatch(w.URL); match != nil {
+		return match[1]
+	}
+	return "en"
+}
+
+// FetchChapter fetches the images of an episode, which are only served to requests coming from the site
+func (w *Webtoons) FetchChapter(f Filterable) (*Chapter, error) {
+	chap := f.(*WebtoonsChapter)
+
+	body, err := fetchPage(chap.URL, w.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	referer, err := resolveUrl(w.URL, "/")
+	if err != nil {
+		return nil, err
+	}
+
+	chapter := &Chapter{
+		Title:     chap.Title,
+		Number:    chap.Number,
+		Label:     chap.Label,
+		Language:  chap.Language,
+		LongStrip: true,
+	}
+	for _, tag := range webtoonsImageTagRe.FindAllString(body, -1) {
+		match := webtoonsImageUrlRe.FindStringSubmatch(tag)
+		if match == nil {
+			continue
+		}
+		image, err := resolveUrl(chap.URL, cleanText(match[1]))
+		if err != nil {
+			return nil, err
+		}
+		chapter.Pages = append(chapter.Pages, Page{
+			Number:  int64(len(chapter.Pages) + 1),
+			URL:     image,
+			Referer: referer,
+		})
+	}
+
+	if len(chapter.Pages) == 0 {
+		return nil, fmt.Errorf("no images found for episode %s", chap.Label)
+	}
+	chapter.PagesCount = int64(len(chapter.Pages))
+
+	return chapter, nil
+}
@@ -0,0 +1,138 @@
+package grabber
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// webtoonsServer serves the saved Webtoons pages, the episode list has two pages
+func webtoonsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	fixture := func(name string) string { return filepath.Join("testdata", "webtoons", name) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/en/fantasy/tower-of-god/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("title_no") != "95" {
+			t.Errorf("episode list requested without its title: %s", r.URL.RawQuery)
+		}
+		// like the site, pages past the last one show the last page again
+		if r.URL.Query().Get("page") == "1" {
+			http.ServeFile(w, r, fixture("list-1.html"))
+			return
+		}
+		http.ServeFile(w, r, fixture("list-2.html"))
+	})
+	mux.HandleFunc("/en/fantasy/tower-of-god/ep-2/viewer", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, fixture("viewer.html"))
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestWebtoons_Test(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "https://www.webtoons.com/en/fantasy/tower-of-god/list?title_no=95", expected: true},
+		{url: "https://www.webtoons.com/en/fantasy/tower-of-god/list?title_no=95&page=3", expected: true},
+		{url: "https://www.webtoons.com/en/fantasy/tower-of-god/ep-1/viewer?title_no=95&episode_no=1", expected: false},
+		{url: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f", expected: false},
+	}
+
+	for _, tt := range tests {
+		if ok, _ := NewWebtoons(&Grabber{URL: tt.url}).Test(); ok != tt.expected {
+			t.Errorf("Test(%s) = %v, want %v", tt.url, ok, tt.expected)
+		}
+	}
+}
+
+func TestWebtoons_FetchTitle(t *testing.T) {
+	ts := webtoonsServer(t)
+
+	w := NewWebtoons(&Grabber{URL: ts.URL + "/en/fantasy/tower-of-god/list?title_no=95"})
+	title, err := w.FetchTitle()
+	if err != nil {
+		t.Fatalf("FetchTitle() error = %v", err)
+	}
+	if title != "Tower of God & Co" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "Tower of God & Co")
+	}
+}
+
+func TestWebtoons_FetchChapters(t *testing.T) {
+	ts := webtoonsServer(t)
+
+	pages := make(map[string]int)
+	handler := ts.Config.Handler
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages[r.URL.Query().Get("page")]++
+		handler.ServeHTTP(w, r)
+	})
+
+	w := NewWebtoons(&Grabber{URL: ts.URL + "/en/fantasy/tower-of-god/list?title_no=95"})
+	if _, err := w.FetchTitle(); err != nil {
+		t.Fatalf("FetchTitle() error = %v", err)
+	}
+	chapters, errs := w.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters() errors = %v", errs)
+	}
+	if pages["1"] != 1 || pages[""] != 0 {
+		t.Errorf("list pages requested %v, want the first page once for the title and the episodes", pages)
+	}
+
+	var titles []string
+	for _, c := range chapters {
+		titles = append(titles, c.GetTitle())
+	}
+	if !reflect.DeepEqual(titles, []string{"Ep. 1 - Prologue", "Ep. 2", "Ep. 3 - Headon"}) {
+		t.Fatalf("episode titles = %v, want the 3 episodes in order", titles)
+	}
+
+	second := chapters[1].(*WebtoonsChapter)
+	if second.Number != 2 || second.Label != "2" || !second.LongStrip || second.Language != "en" {
+		t.Errorf("episode = %+v, want long strip episode 2", second)
+	}
+	if second.URL != ts.URL+"/en/fantasy/tower-of-god/ep-2/viewer?title_no=95&episode_no=2" {
+		t.Errorf("URL = %q, want the viewer on the host of the list", second.URL)
+	}
+}
+
+func TestWebtoons_FetchChapter(t *testing.T) {
+	ts := webtoonsServer(t)
+
+	w := NewWebtoons(&Grabber{URL: ts.URL + "/en/fantasy/tower-of-god/list?title_no=95"})
+	chapter, err := w.FetchChapter(&WebtoonsChapter{
+		Chapter: Chapter{Number: 2, Label: "2", Title: "Ep. 2", LongStrip: true},
+		URL:     ts.URL + "/en/fantasy/tower-of-god/ep-2/viewer?title_no=95&episode_no=2",
+	})
+	if err != nil {
+		t.Fatalf("FetchChapter() error = %v", err)
+	}
+
+	if !chapter.LongStrip || chapter.PagesCount != 3 {
+		t.Fatalf("chapter = %+v, want a long strip with 3 images", chapter)
+	}
+
+	expected := []Page{
+		{Number: 1, URL: ts.URL + "/images/95/2/001.jpg?type=q90", Referer: ts.URL + "/"},
+		{Number: 2, URL: ts.URL + "/images/95/2/002.jpg?type=q90", Referer: ts.URL + "/"},
+		{Number: 3, URL: ts.URL + "/images/95/2/003.jpg?type=q90&v=2", Referer: ts.URL + "/"},
+	}
+	if !reflect.DeepEqual(chapter.Pages, expected) {
+		t.Errorf("Pages = %+v, want %+v", chapter.Pages, expected)
+	}
+}
+
+func TestWebtoons_Language(t *testing.T) {
+	w := NewWebtoons(&Grabber{URL: "https://www.webtoons.com/zh-hant/fantasy/tower-of-god/list?title_no=95"})
+	if lang := w.language(); lang != "zh-hant" {
+		t.Errorf("language() = %q, want %q", lang, "zh-hant")
+	}
+}
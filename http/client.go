@@ -11,6 +11,8 @@ type RequestParams struct {
 	URL     string
 	Referer string
 	Headers map[string]string
+	// Timeout replaces the timeout of Client for this request, when set
+	Timeout time.Duration
 }
 
 // Client is a custom HTTP client with default settings
@@ -20,7 +22,40 @@ var Client = &http.Client{
 
 // Get performs a GET request with the given parameters
 func Get(params RequestParams) (io.ReadCloser, error) {
-	req, err := http.NewRequest("GET", params.URL, nil)
+	body, _, err := GetWithHeaders(params)
+	return body, err
+}
+
+// GetWithHeaders performs a GET request with the given parameters, also returning the response headers
+func GetWithHeaders(params RequestParams) (io.ReadCloser, http.Header, error) {
+	resp, err := do("GET", params, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp.Body, resp.Header, nil
+}
+
+// Post performs a POST request sending body with the given content type
+func Post(params RequestParams, contentType string, body io.Reader) (io.ReadCloser, error) {
+	// copy the headers so the caller's map is left untouched
+	headers := map[string]string{"Content-Type": contentType}
+	for key, value := range params.Headers {
+		headers[key] = value
+	}
+	params.Headers = headers
+
+	resp, err := do("POST", params, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// do performs a request, returning an *HTTPError for non 200 responses
+func do(method string, params RequestParams, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, params.URL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +73,14 @@ func Get(params RequestParams) (io.ReadCloser, error) {
 		req.Header.Set(key, value)
 	}
 
-	resp, err := Client.Do(req)
+	client := Client
+	if params.Timeout > 0 {
+		c := *Client
+		c.Timeout = params.Timeout
+		client = &c
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +94,7 @@ func Get(params RequestParams) (io.ReadCloser, error) {
 		}
 	}
 
-	return resp.Body, nil
+	return resp, nil
 }
 
 // HTTPError represents an HTTP error
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/colors"
+	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/packer"
+)
+
+// fetchList fetches every title of a list with the same options, one output directory per title, and ends with a
+// summary of the titles that succeeded and failed. An error is only returned when no title succeeded.
+func fetchList(lister grabber.ListFetcher, opts Options, fetch func(url string, opts Options) (string, error)) (string, error) {
+	list, err := lister.FetchList()
+	if err != nil {
+		return "", fmt.Errorf("error fetching list: %w", err)
+	}
+	if len(list.Titles) == 0 {
+		return "", fmt.Errorf("list %s has no titles", list.Name)
+	}
+
+	output := ""
+	summary := ""
+	failed := 0
+	baseDir := opts.OutputDir
+	for i, title := range list.Titles {
+		colors.InfoPrintf("[%d/%d] %s\n", i+1, len(list.Titles), title.Title)
+
+		titleOpts := opts
+		titleOpts.OutputDir = filepath.Join(baseDir, packer.GetSeriesDirname(title.Title))
+		content, err := fetch(title.URL, titleOpts)
+		if err != nil {
+			colors.ErrorPrintf("Error fetching %s: %v\n", title.Title, err)
+			summary += fmt.Sprintf("  ✗ %s: %v\n", title.Title, err)
+			failed++
+			continue
+		}
+
+		output += content + "\n"
+		summary += fmt.Sprintf("  ✓ %s\n", title.Title)
+	}
+
+	output += fmt.Sprintf("List: %s (%d titles, %d succeeded, %d failed)\n%s",
+		list.Name, len(list.Titles), len(list.Titles)-failed, failed, summary)
+
+	if failed == len(list.Titles) {
+		return "", fmt.Errorf("every title of list %s failed:\n%s", list.Name, strings.TrimSuffix(summary, "\n"))
+	}
+
+	return output, nil
+}
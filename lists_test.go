@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+// fakeList is a ListFetcher returning a fixed list
+type fakeList struct {
+	list *grabber.TitleList
+}
+
+func (f *fakeList) FetchList() (*grabber.TitleList, error) { return f.list, nil }
+
+func TestFetchList(t *testing.T) {
+	lister := &fakeList{list: &grabber.TitleList{Name: "Reading Group", Titles: []grabber.SearchResult{
+		{Title: "First", URL: "https://example.com/first"},
+		{Title: "Second", URL: "https://example.com/second"},
+	}}}
+
+	outputDirs := make(map[string]string)
+	fetch := func(url string, opts Options) (string, error) {
+		outputDirs[url] = opts.OutputDir
+		if strings.HasSuffix(url, "second") {
+			return "", errors.New("no chapters found for range 1-3")
+		}
+		return "Title: First\n", nil
+	}
+
+	output, err := fetchList(lister, Options{ChapterRange: "1-3", OutputDir: "out"}, fetch)
+	if err != nil {
+		t.Fatalf("fetchList() error = %v", err)
+	}
+
+	if outputDirs["https://example.com/first"] != filepath.Join("out", "First") {
+		t.Errorf("output directories = %v, want one per title", outputDirs)
+	}
+
+	for _, expected := range []string{
+		"Title: First",
+		"List: Reading Group (2 titles, 1 succeeded, 1 failed)",
+		"✓ First",
+		"✗ Second: no chapters found for range 1-3",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	// a list where every title fails is an error
+	lister.list.Titles = lister.list.Titles[1:]
+	if _, err = fetchList(lister, Options{}, fetch); err == nil || !strings.Contains(err.Error(), "✗ Second") {
+		t.Errorf("fetchList() error = %v, want the failures listed", err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/auth"
+	"github.sammcclenaghan.com/mango/colors"
+	"github.sammcclenaghan.com/mango/grabber"
+	"golang.org/x/term"
+)
+
+// runLogin handles the login subcommand: it logs in with a MangaDex personal API client and stores the session
+// tokens at path. The client secret is read from the flag, MANGO_CLIENT_SECRET or stdin, and the password from
+// MANGO_PASSWORD or stdin. Secrets typed on a terminal aren't echoed.
+func runLogin(argv []string, path string, stdin io.Reader) (string, error) {
+	var username, clientId, clientSecret, tokenUrl string
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--client-id" && i+1 < len(argv) {
+			clientId = argv[i+1]
+			i++
+		} else if arg == "--client-secret" && i+1 < len(argv) {
+			clientSecret = argv[i+1]
+			i++
+		} else if arg == "--auth-url" && i+1 < len(argv) {
+			tokenUrl = argv[i+1]
+			i++
+		} else if !strings.HasPrefix(arg, "--") {
+			username = arg
+		}
+	}
+
+	if username == "" || clientId == "" {
+		return "", fmt.Errorf("missing login details. Usage: mango login <username> --client-id <id> [--client-secret <secret>]")
+	}
+
+	// both secrets may come from the same stdin, they share its buffer
+	reader := bufio.NewReader(stdin)
+
+	if clientSecret == "" {
+		clientSecret = os.Getenv("MANGO_CLIENT_SECRET")
+	}
+	if clientSecret == "" {
+		secret, err := readSecret("Client secret: ", stdin, reader)
+		if err != nil {
+			return "", fmt.Errorf("error reading client secret: %w", err)
+		}
+		if secret == "" {
+			return "", fmt.Errorf("missing client secret")
+		}
+		clientSecret = secret
+	}
+
+	password := os.Getenv("MANGO_PASSWORD")
+	if password == "" {
+		secret, err := readSecret("Password: ", stdin, reader)
+		if err != nil {
+			return "", fmt.Errorf("error reading password: %w", err)
+		}
+		password = secret
+	}
+
+	creds, err := auth.Login(tokenUrl, clientId, clientSecret, username, password)
+	if err != nil {
+		return "", err
+	}
+
+	if err = creds.Save(path); err != nil {
+		return "", fmt.Errorf("error saving credentials: %w", err)
+	}
+
+	return fmt.Sprintf("Logged in as %s, credentials saved to %s", username, path), nil
+}
+
+// readSecret prompts for a secret, read without echo when stdin is a terminal and from the next line of reader
+// otherwise
+func readSecret(prompt string, stdin io.Reader, reader *bufio.Reader) (string, error) {
+	fmt.Print(prompt)
+
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		secret, err := term.ReadPassword(int(f.Fd()))
+		// the typed newline isn't echoed either
+		fmt.Println()
+		return string(secret), err
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// runLogout handles the logout subcommand: it deletes the session tokens stored at path
+func runLogout(path string) (string, error) {
+	if err := auth.Remove(path); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Logged out, removed %s", path), nil
+}
+
+// storedSession returns the session saved by mango login, or ErrLoginRequired when the user never logged in
+func storedSession() (grabber.TokenSource, error) {
+	path, err := auth.DefaultPath()
+	if err != nil {
+		return nil, grabber.ErrLoginRequired
+	}
+
+	session, err := auth.Open(path)
+	if errors.Is(err, auth.ErrNotLoggedIn) {
+		return nil, grabber.ErrLoginRequired
+	}
+	return session, err
+}
+
+// loginSession returns the stored session, or logs in with the MANGO_USERNAME, MANGO_PASSWORD, MANGO_CLIENT_ID
+// and MANGO_CLIENT_SECRET environment variables when the user never ran mango login. The token endpoint can be
+// changed with MANGO_AUTH_URL. Logging in costs a request to the token endpoint, only follows, which can't do
+// without an account, uses it.
+func loginSession() (grabber.TokenSource, error) {
+	session, err := storedSession()
+	if !errors.Is(err, grabber.ErrLoginRequired) {
+		return session, err
+	}
+
+	username, clientId, clientSecret := os.Getenv("MANGO_USERNAME"), os.Getenv("MANGO_CLIENT_ID"), os.Getenv("MANGO_CLIENT_SECRET")
+	if username == "" || clientId == "" || clientSecret == "" {
+		return nil, grabber.ErrLoginRequired
+	}
+
+	creds, err := auth.Login(os.Getenv("MANGO_AUTH_URL"), clientId, clientSecret, username, os.Getenv("MANGO_PASSWORD"))
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewSession("", creds), nil
+}
+
+// withSession authenticates the settings with the stored session, requests stay anonymous when the user never logged
+// in
+func withSession(settings grabber.Settings) grabber.Settings {
+	session, err := storedSession()
+	if err != nil {
+		if !errors.Is(err, grabber.ErrLoginRequired) {
+			colors.WarningPrintf("Warning: %v, continuing without logging in\n", err)
+		}
+		return settings
+	}
+
+	settings.Auth = session
+	return settings
+}
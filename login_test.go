@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/auth"
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+func TestRunLogin(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("password") != "hunter2" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "access", "refresh_token": "refresh", "expires_in": 900}`))
+	}))
+	defer ts.Close()
+
+	t.Setenv("MANGO_PASSWORD", "")
+	path := filepath.Join(t.TempDir(), "mangadex.json")
+	argv := []string{"reader", "--client-id", "client", "--client-secret", "secret", "--auth-url", ts.URL}
+
+	if _, err := runLogin(argv[:1], path, strings.NewReader("hunter2\n")); err == nil {
+		t.Error("runLogin() error = nil without client credentials")
+	}
+
+	if _, err := runLogin(argv, path, strings.NewReader("wrong\n")); err == nil {
+		t.Error("runLogin() error = nil for a wrong password")
+	}
+
+	content, err := runLogin(argv, path, strings.NewReader("hunter2\n"))
+	if err != nil {
+		t.Fatalf("runLogin() error = %v", err)
+	}
+	if !strings.Contains(content, "Logged in as reader") {
+		t.Errorf("runLogin() = %q, want a confirmation", content)
+	}
+
+	creds, err := auth.Load(path)
+	if err != nil {
+		t.Fatalf("credentials were not saved: %v", err)
+	}
+	if creds.TokenUrl != ts.URL || creds.RefreshToken != "refresh" {
+		t.Errorf("saved credentials = %+v, want the stub endpoint and its tokens", creds)
+	}
+
+	// without the flag the client secret comes from the environment or stdin, before the password
+	noSecret := []string{"reader", "--client-id", "client", "--auth-url", ts.URL}
+	if _, err := runLogin(noSecret, path, strings.NewReader("secret\nhunter2\n")); err != nil {
+		t.Errorf("runLogin() error = %v with the client secret on stdin", err)
+	}
+	t.Setenv("MANGO_CLIENT_SECRET", "secret")
+	if _, err := runLogin(noSecret, path, strings.NewReader("hunter2\n")); err != nil {
+		t.Errorf("runLogin() error = %v with the client secret in MANGO_CLIENT_SECRET", err)
+	}
+	t.Setenv("MANGO_CLIENT_SECRET", "")
+	if _, err := runLogin(noSecret, path, strings.NewReader("\nhunter2\n")); err == nil {
+		t.Error("runLogin() error = nil without a client secret")
+	}
+
+	if _, err = runLogout(path); err != nil {
+		t.Fatalf("runLogout() error = %v", err)
+	}
+	if _, err = auth.Load(path); err != auth.ErrNotLoggedIn {
+		t.Errorf("credentials still present after logout: %v", err)
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "access", "refresh_token": "refresh", "expires_in": 900}`))
+	}))
+	defer ts.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MANGO_USERNAME", "reader")
+	t.Setenv("MANGO_PASSWORD", "hunter2")
+	t.Setenv("MANGO_CLIENT_ID", "client")
+	t.Setenv("MANGO_CLIENT_SECRET", "secret")
+	t.Setenv("MANGO_AUTH_URL", ts.URL)
+
+	// the environment login is left to follows
+	if settings := withSession(grabber.Settings{}); settings.Auth != nil || requests != 0 {
+		t.Errorf("withSession() = %+v after %d requests, want anonymous settings without logging in", settings, requests)
+	}
+	if session, err := loginSession(); err != nil || session == nil || requests != 1 {
+		t.Errorf("loginSession() = %v, %v after %d requests, want a session from the environment", session, err, requests)
+	}
+
+	// a stored session is used as is
+	path, err := auth.DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runLogin([]string{"reader", "--client-id", "client", "--auth-url", ts.URL}, path, strings.NewReader("")); err != nil {
+		t.Fatalf("runLogin() error = %v", err)
+	}
+	requests = 0
+	if settings := withSession(grabber.Settings{}); settings.Auth == nil || requests != 0 {
+		t.Errorf("withSession() = %+v after %d requests, want the stored session", settings, requests)
+	}
+}
@@ -1,52 +1,117 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.sammcclenaghan.com/mango/auth"
 	"github.sammcclenaghan.com/mango/colors"
 	"github.sammcclenaghan.com/mango/converter"
 	"github.sammcclenaghan.com/mango/downloader"
 	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/http"
 	"github.sammcclenaghan.com/mango/packer"
 	"github.sammcclenaghan.com/mango/ranges"
 )
 
+// Options holds the settings used to select, download, pack and convert chapters
+type Options struct {
+	ChapterRange  string
+	VolumeRange   string
+	Download      bool
+	SaveCBZ       bool
+	ConvertToAZW3 bool
+	ConvertToEPUB bool
+	OutputDir     string
+	ListOnly      bool
+	// Settings are passed to the site grabber
+	Settings grabber.Settings
+	// ByVolume packs one CBZ per volume instead of a single bundle
+	ByVolume bool
+	// Info shows the series metadata instead of the chapters
+	Info bool
+	// ChapterLabels selects chapters without a number by label, like "Extra" or "Oneshot"
+	ChapterLabels []string
+	// MarkRead marks the chapters read on the site account once they are packed
+	MarkRead bool
+	// SkipRead leaves out the chapters already read on the site account
+	SkipRead bool
+}
+
 // FetchURLContent fetches the content from the given URL and returns it as a string.
-func FetchURLContent(url string, chapterRange string, download bool, saveCBZ bool, convertToAZW3 bool, convertToEPUB bool, outputDir string, listOnly bool) (string, error) {
+func FetchURLContent(url string, opts Options) (string, error) {
+	// Validate the requested ranges before hitting the network
+	if _, err := parseChapterSelection(opts.ChapterRange, opts.ChapterLabels); err != nil {
+		return "", fmt.Errorf("invalid chapter range '%s': %w", opts.ChapterRange, err)
+	}
+	if _, err := ranges.Parse(opts.VolumeRange); err != nil {
+		return "", fmt.Errorf("invalid volume range '%s': %w", opts.VolumeRange, err)
+	}
+
 	// Create a base grabber
+	if len(opts.Settings.LanguagePriority()) == 0 {
+		opts.Settings.Language = "en" // default to English
+	}
 	g := &grabber.Grabber{
-		URL: url,
-		Settings: grabber.Settings{
-			Language: "en", // default to English
-		},
+		URL:      url,
+		Settings: opts.Settings,
 	}
 
-	// Create MangaDx grabber
-	mangadx := grabber.NewMangadx(g)
-
-	// Test if this is a supported site
-	isSupported, err := mangadx.Test()
+	// Resolve the grabber of the site handling this URL
+	site, err := grabber.New(g)
 	if err != nil {
-		return "", fmt.Errorf("error testing site: %w", err)
+		return "", err
 	}
 
-	if !isSupported {
-		return "", fmt.Errorf("unsupported site: %s", url)
+	// Lists, chapters and groups are told apart from titles by the site
+	kind := grabber.TitleURL
+	if classifier, ok := site.(grabber.Classifier); ok {
+		kind = classifier.Classify()
+	}
+	switch kind {
+	case grabber.ListURL:
+		lister, ok := site.(grabber.ListFetcher)
+		if !ok {
+			return "", fmt.Errorf("%s is a list, which this site can't download", url)
+		}
+		return fetchList(lister, opts, FetchURLContent)
+	case grabber.ChapterURL:
+		resolver, ok := site.(grabber.ChapterResolver)
+		if !ok {
+			return "", fmt.Errorf("%s is a chapter, which this site can't download on its own", url)
+		}
+		return fetchChapterURL(resolver, opts)
+	case grabber.GroupURL:
+		return "", fmt.Errorf("%s is a scanlation group, use the URL of a title, chapter or list instead", url)
 	}
 
 	// Fetch the title
-	title, err := mangadx.FetchTitle()
+	title, err := fetchTitle(site)
 	if err != nil {
-		return "", fmt.Errorf("error fetching title: %w", err)
+		return "", err
+	}
+
+	if opts.Info {
+		return seriesInfo(site, title)
 	}
 
 	// Fetch chapters
-	chapters, errs := mangadx.FetchChapters()
+	chapters, errs := site.FetchChapters()
 	if len(errs) > 0 {
+		var noChapters *grabber.NoChaptersError
+		if len(errs) == 1 && errors.As(errs[0], &noChapters) {
+			return "", noChapters
+		}
+		var incomplete *grabber.IncompleteFeedError
+		if len(errs) == 1 && errors.As(errs[0], &incomplete) {
+			return "", fmt.Errorf("error fetching chapters: %w, refusing to work on a partial chapter list", incomplete)
+		}
 		return "", fmt.Errorf("errors fetching chapters: %v", errs)
 	}
 
@@ -55,89 +120,246 @@ func FetchURLContent(url string, chapterRange string, download bool, saveCBZ boo
 	output += fmt.Sprintf("Found %d chapters:\n\n", len(chapters))
 
 	// If a specific chapter range is requested, fetch those chapters
-	if listOnly {
-		return listAvailableChapters(title, chapters)
+	if opts.ListOnly {
+		return listAvailableChapters(title, chapters, opts.Settings)
 	}
 
-	if chapterRange != "" {
-		colors.DebugPrintf("Debug: Looking for chapter range %s\n", chapterRange)
+	if opts.ChapterRange != "" || opts.VolumeRange != "" || len(opts.ChapterLabels) > 0 {
+		colors.DebugPrintf("Debug: Looking for chapter range %s volume range %s\n", opts.ChapterRange, opts.VolumeRange)
 		colors.DebugPrintf("Debug: Available chapters: %d\n", len(chapters))
-		return fetchChapterRange(mangadx, chapters, chapterRange, title, download, saveCBZ, convertToAZW3, convertToEPUB, outputDir)
+		return fetchChapterRange(site, chapters, title, opts)
 	}
 
 	// Otherwise, list all chapters
 	for _, chapter := range chapters {
-		output += fmt.Sprintf("Chapter %.1f: %s (%s)\n",
-			chapter.GetNumber(),
+		output += fmt.Sprintf("Chapter %s: %s (%s)%s\n",
+			listedNumber(chapter),
 			chapter.GetTitle(),
-			chapter.GetLanguage())
+			chapter.GetLanguage(),
+			chapterDetails(chapter))
 	}
 
 	return output, nil
 }
 
-// fetchChapterRange fetches pages for chapters within the specified range
-func fetchChapterRange(mangadx *grabber.Mangadx, chapters grabber.Filterables, chapterRange string, title string, download bool, saveCBZ bool, convertToAZW3 bool, convertToEPUB bool, outputDir string) (string, error) {
-	// Parse the chapter range
-	parsedRanges, err := ranges.Parse(chapterRange)
+// listedNumber formats the chapter number as shown in chapter listings, keeping one decimal for numbered chapters
+func listedNumber(chapter grabber.Filterable) string {
+	if grabber.IsNumbered(chapter) {
+		return fmt.Sprintf("%.1f", chapter.GetNumber())
+	}
+	return grabber.ChapterName(chapter)
+}
+
+// fetchTitle fetches the title of the site, telling how to include titles excluded by the content rating filter
+func fetchTitle(site grabber.GrabberInterface) (string, error) {
+	title, err := site.FetchTitle()
 	if err != nil {
-		return "", fmt.Errorf("invalid chapter range '%s': %w", chapterRange, err)
+		var rating *grabber.ContentRatingError
+		if errors.As(err, &rating) {
+			return "", fmt.Errorf("%w\nUse --content-rating %s,%s to include it", err, strings.Join(rating.Allowed, ","), rating.Rating)
+		}
+		return "", fmt.Errorf("error fetching title: %w", err)
 	}
 
-	// Find matching chapters and deduplicate by chapter number
-	var selectedChapters []grabber.Filterable
-	seenChapters := make(map[float64]bool)
-	duplicateCount := 0
+	return title, nil
+}
+
+// chapterSelection holds the requested chapters: number ranges and labels like "Extra"
+type chapterSelection struct {
+	ranges []ranges.Range
+	labels []string
+}
+
+// parseChapterSelection parses the chapter range and the labels of chapters without a number, like "Extra"
+func parseChapterSelection(rnge string, labels []string) (chapterSelection, error) {
+	parsed, err := ranges.Parse(rnge)
+	if err != nil {
+		return chapterSelection{}, err
+	}
+
+	return chapterSelection{ranges: parsed, labels: labels}, nil
+}
+
+// isEmpty reports whether no chapters were selected, in which case every chapter matches
+func (sel chapterSelection) isEmpty() bool {
+	return len(sel.ranges) == 0 && len(sel.labels) == 0
+}
+
+// matches reports whether the chapter is selected, by number for numbered chapters and by label otherwise
+func (sel chapterSelection) matches(chapter grabber.Filterable) bool {
+	if sel.isEmpty() {
+		return true
+	}
+
+	if grabber.IsNumbered(chapter) {
+		return ranges.ContainsAny(sel.ranges, chapter.GetNumber())
+	}
+
+	for _, label := range sel.labels {
+		if strings.EqualFold(label, strings.TrimSpace(chapter.GetLabel())) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionDescription describes the requested chapter and volume ranges
+func selectionDescription(opts Options) string {
+	var parts []string
+	if opts.ChapterRange != "" {
+		parts = append(parts, "range "+opts.ChapterRange)
+	}
+	if len(opts.ChapterLabels) > 0 {
+		parts = append(parts, "chapters "+strings.Join(opts.ChapterLabels, ", "))
+	}
+	if opts.VolumeRange != "" {
+		parts = append(parts, "volumes "+opts.VolumeRange)
+	}
+	if len(parts) == 0 {
+		return "all chapters"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// matchesVolumes checks if the chapter volume is within any of the volume ranges
+func matchesVolumes(volumeRanges []ranges.Range, chapter grabber.Filterable) bool {
+	vol, err := strconv.ParseFloat(chapter.GetVolume(), 64)
+	if err != nil {
+		// chapters without a (numeric) volume never match a volume selection
+		return false
+	}
+	return ranges.ContainsAny(volumeRanges, vol)
+}
+
+// chapterDetails returns the volume and scanlation group annotations used when listing a chapter
+func chapterDetails(chapter grabber.Filterable) string {
+	details := ""
+	if chapter.GetVolume() != "" {
+		details += fmt.Sprintf(" [Vol %s]", chapter.GetVolume())
+	}
+	if len(chapter.GetGroups()) > 0 {
+		details += fmt.Sprintf(" by %s", strings.Join(chapter.GetGroups(), ", "))
+	}
+	if u, ok := chapter.(grabber.UnavailableChapter); ok && u.IsUnavailable() {
+		if link := u.ExternalLink(); link != "" {
+			details += fmt.Sprintf(" [external: %s]", link)
+		} else {
+			details += " [unavailable]"
+		}
+	}
+	return details
+}
+
+// chapterSpan returns the first and last of the chapters, like "3-4"
+func chapterSpan(chapters []*grabber.Chapter) string {
+	first, last := chapters[0], chapters[0]
 	for _, chapter := range chapters {
-		if ranges.ContainsAny(parsedRanges, chapter.GetNumber()) {
-			// Only add if we haven't seen this chapter number before
-			if !seenChapters[chapter.GetNumber()] {
-				selectedChapters = append(selectedChapters, chapter)
-				seenChapters[chapter.GetNumber()] = true
-				colors.FetchedPrintf("fetching %s chapter %.0f\n", title, chapter.GetNumber())
-			} else {
-				duplicateCount++
-				colors.DebugPrintf("Debug: Skipping duplicate chapter %.1f (%s)\n", chapter.GetNumber(), chapter.GetLanguage())
-			}
+		if grabber.ChapterLess(chapter, first) {
+			first = chapter
+		}
+		if grabber.ChapterLess(last, chapter) {
+			last = chapter
 		}
 	}
 
-	if duplicateCount > 0 {
-		colors.DebugPrintf("Debug: Skipped %d duplicate chapters\n", duplicateCount)
+	if first == last {
+		return grabber.ChapterName(first)
+	}
+	return grabber.ChapterName(first) + "-" + grabber.ChapterName(last)
+}
+
+// isNotFound reports whether the error is a 404 response
+func isNotFound(err error) bool {
+	var httpErr *http.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == 404
+}
+
+// unavailableNote explains why a chapter is not downloaded, pointing to its official release when there is one
+func unavailableNote(chapter grabber.Filterable) string {
+	if u, ok := chapter.(grabber.UnavailableChapter); ok && u.ExternalLink() != "" {
+		return fmt.Sprintf("Chapter %s is hosted externally, read it at %s\n", grabber.ChapterName(chapter), u.ExternalLink())
+	}
+	return fmt.Sprintf("Chapter %s is unavailable on this site\n", grabber.ChapterName(chapter))
+}
+
+// fetchChapterRange fetches pages for chapters within the specified chapter and volume ranges
+func fetchChapterRange(site grabber.GrabberInterface, chapters grabber.Filterables, title string, opts Options) (string, error) {
+	// Parse the chapter and volume ranges
+	chapterSel, err := parseChapterSelection(opts.ChapterRange, opts.ChapterLabels)
+	if err != nil {
+		return "", fmt.Errorf("invalid chapter range '%s': %w", opts.ChapterRange, err)
+	}
+	volumeRanges, err := ranges.Parse(opts.VolumeRange)
+	if err != nil {
+		return "", fmt.Errorf("invalid volume range '%s': %w", opts.VolumeRange, err)
+	}
+	selection := selectionDescription(opts)
+	marker, err := readMarker(site, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Find matching chapters
+	var matchingChapters grabber.Filterables
+	for _, chapter := range chapters {
+		if !chapterSel.matches(chapter) {
+			continue
+		}
+		if len(volumeRanges) > 0 && !matchesVolumes(volumeRanges, chapter) {
+			continue
+		}
+		matchingChapters = append(matchingChapters, chapter)
+	}
+
+	// Leave out the chapters the user already read
+	if opts.SkipRead && len(matchingChapters) > 0 {
+		unread, skipped, err := skipRead(marker, matchingChapters)
+		if err != nil {
+			return "", err
+		}
+		if len(unread) == 0 {
+			return fmt.Sprintf("Title: %s\nAll chapters in %s were already read.\n", title, selection), nil
+		}
+		if skipped > 0 {
+			colors.InfoPrintf("Skipping %d chapter releases already read\n", skipped)
+		}
+		matchingChapters = unread
+	}
+
+	// Deduplicate by chapter, picking the release of the preferred scanlation group
+	selectedChapters := grabber.Dedupe(matchingChapters, opts.Settings)
+	for _, chapter := range selectedChapters {
+		colors.FetchedPrintf("fetching %s chapter %s%s\n", title, grabber.ChapterName(chapter), chapterDetails(chapter))
+	}
+
+	if duplicateCount := len(matchingChapters) - len(selectedChapters); duplicateCount > 0 {
+		colors.DebugPrintf("Debug: Skipped %d duplicate or blocked chapters\n", duplicateCount)
 	}
 
 	if len(selectedChapters) == 0 {
 		// Create a more helpful error message with suggestions
-		var availableNumbers []float64
-		for _, ch := range chapters {
-			availableNumbers = append(availableNumbers, ch.GetNumber())
-		}
+		available := grabber.Dedupe(chapters, grabber.Settings{})
+		grabber.SortChapters(available)
 
-		// Sort chapters for better display
-		for i := 0; i < len(availableNumbers); i++ {
-			for j := i + 1; j < len(availableNumbers); j++ {
-				if availableNumbers[i] > availableNumbers[j] {
-					availableNumbers[i], availableNumbers[j] = availableNumbers[j], availableNumbers[i]
-				}
+		var availableNumbers []float64
+		for _, ch := range available {
+			if grabber.IsNumbered(ch) {
+				availableNumbers = append(availableNumbers, ch.GetNumber())
 			}
 		}
 
 		// Build available chapters string (limit to first 20 for readability)
 		availableStr := ""
-		displayCount := len(availableNumbers)
+		displayCount := len(available)
 		if displayCount > 20 {
 			displayCount = 20
 		}
 
 		for i := 0; i < displayCount; i++ {
-			if availableNumbers[i] == float64(int64(availableNumbers[i])) {
-				availableStr += fmt.Sprintf("%.0f ", availableNumbers[i])
-			} else {
-				availableStr += fmt.Sprintf("%.1f ", availableNumbers[i])
-			}
+			availableStr += grabber.ChapterName(available[i]) + " "
 		}
 
-		if len(availableNumbers) > 20 {
+		if len(available) > 20 {
 			availableStr += "... (and more)"
 		}
 
@@ -161,157 +383,169 @@ func fetchChapterRange(mangadx *grabber.Mangadx, chapters grabber.Filterables, c
 			}
 		}
 
-		return "", fmt.Errorf("no chapters found for range %s.\nAvailable chapters: %s%s", chapterRange, availableStr, suggestions)
+		return "", fmt.Errorf("no chapters found for %s.\nAvailable chapters: %s%s", selection, availableStr, suggestions)
 	}
 
 	// Build initial output
 	output := fmt.Sprintf("Title: %s\n", title)
-	output += fmt.Sprintf("Found %d unique chapters in range %s:\n\n", len(selectedChapters), chapterRange)
+	output += fmt.Sprintf("Found %d unique chapters in %s:\n\n", len(selectedChapters), selection)
 
-	if !download {
+	if !opts.Download {
 		// Just list the matching chapters
 		for _, chapter := range selectedChapters {
-			output += fmt.Sprintf("Chapter %.1f: %s (%s)\n",
-				chapter.GetNumber(),
+			output += fmt.Sprintf("Chapter %s: %s (%s)%s\n",
+				listedNumber(chapter),
 				chapter.GetTitle(),
-				chapter.GetLanguage())
+				chapter.GetLanguage(),
+				chapterDetails(chapter))
 		}
 		return output, nil
 	}
 
+	// Chapters hosted elsewhere or flagged unavailable can't be downloaded, point to the official release instead
+	var downloadable grabber.Filterables
+	notes := ""
+	for _, chapter := range selectedChapters {
+		if grabber.IsDownloadable(chapter) {
+			downloadable = append(downloadable, chapter)
+		} else {
+			notes += unavailableNote(chapter)
+		}
+	}
+	if len(downloadable) == 0 {
+		return "", fmt.Errorf("no chapters can be downloaded for %s.\n%s", selection, strings.TrimSuffix(notes, "\n"))
+	}
+	output += notes
+	selectedChapters = downloadable
+
 	// Download mode - process each chapter
 	var allFiles []*downloader.File
 	var downloadedChapters []*grabber.Chapter
-	chapterFiles := make(map[float64][]*downloader.File) // Track files by chapter number
+	chapterFiles := make(map[string][]*downloader.File) // Track files by chapter key
 
 	for _, selectedChapter := range selectedChapters {
-		colors.FetchedPrintf("fetching %s chapter %.0f\n", title, selectedChapter.GetNumber())
+		colors.FetchedPrintf("fetching %s chapter %s\n", title, grabber.ChapterName(selectedChapter))
 
 		// Debug: Print chapter ID before fetching
-		if mangadxChap, ok := selectedChapter.(*grabber.MangadxChapter); ok {
-			colors.DebugPrintf("Debug: Fetching chapter ID: %s\n", mangadxChap.Id)
+		if id := selectedChapter.GetId(); id != "" {
+			colors.DebugPrintf("Debug: Fetching chapter ID: %s\n", id)
 		}
 
 		// Fetch the chapter with its pages
-		chapterWithPages, err := mangadx.FetchChapter(selectedChapter)
+		chapterWithPages, err := site.FetchChapter(selectedChapter)
 		if err != nil {
-			if strings.Contains(err.Error(), "404") {
-				colors.ErrorPrintf("Chapter %.0f not available (404 - possibly licensed/removed)\n", selectedChapter.GetNumber())
+			if isNotFound(err) {
+				colors.ErrorPrintf("Chapter %s not available (404 - possibly licensed/removed)\n", grabber.ChapterName(selectedChapter))
 			} else {
-				colors.ErrorPrintf("Error fetching chapter %.0f: %v\n", selectedChapter.GetNumber(), err)
+				colors.ErrorPrintf("Error fetching chapter %s: %v\n", grabber.ChapterName(selectedChapter), err)
 			}
 			continue
 		}
-
-		downloadedChapters = append(downloadedChapters, chapterWithPages)
+		// Keep the label of the listed chapter, grabbers may only return its number
+		if chapterWithPages.Label == "" {
+			chapterWithPages.Label = selectedChapter.GetLabel()
+		}
 
 		// Download the chapter pages
-		colors.DownloadedPrintf("downloading %s chapter %.0f\n", title, chapterWithPages.Number)
+		colors.DownloadedPrintf("downloading %s chapter %s\n", title, grabber.ChapterName(chapterWithPages))
 		progressCallback := func(page, progress int, err error) {
 			if err != nil {
 				colors.ErrorPrintf("Error downloading page %d: %v\n", page, err)
 			}
 		}
 
-		files, err := downloader.FetchChapter(mangadx, chapterWithPages, progressCallback)
+		files, err := downloader.FetchChapter(site, chapterWithPages, progressCallback)
 		if err != nil {
-			if strings.Contains(err.Error(), "404") {
-				colors.ErrorPrintf("Chapter %.0f pages not available (404 - possibly licensed/removed)\n", chapterWithPages.Number)
+			if isNotFound(err) {
+				colors.ErrorPrintf("Chapter %s pages not available (404 - possibly licensed/removed)\n", grabber.ChapterName(chapterWithPages))
 			} else {
-				colors.ErrorPrintf("Error downloading chapter %.0f: %v\n", chapterWithPages.Number, err)
+				colors.ErrorPrintf("Error downloading chapter %s: %v\n", grabber.ChapterName(chapterWithPages), err)
 			}
 			continue
 		}
 
-		// Store files by chapter number for proper organization
-		chapterFiles[chapterWithPages.Number] = files
+		downloadedChapters = append(downloadedChapters, chapterWithPages)
+
+		// Store files by chapter for proper organization
+		chapterFiles[grabber.ChapterKey(chapterWithPages)] = files
 		allFiles = append(allFiles, files...)
-		colors.SavedPrintf("saving %s chapter %.0f\n", title, chapterWithPages.Number)
+		colors.SavedPrintf("saving %s chapter %s\n", title, grabber.ChapterName(chapterWithPages))
 	}
 
 	if len(downloadedChapters) == 0 {
-		return "", fmt.Errorf("no chapters could be downloaded.\n\nThis manga may be:\n• Officially licensed and removed from MangaDx\n• Restricted in your region\n• Temporarily unavailable\n\nSuggestions:\n• Try a different manga series\n• Check official sources like Viz, Crunchyroll, or publisher websites\n• Use --list to verify available chapters")
+		return "", fmt.Errorf("no chapters could be downloaded.\n\nThis manga may be:\n• Officially licensed and removed from the site\n• Restricted in your region\n• Temporarily unavailable\n\nSuggestions:\n• Try a different manga series\n• Check official sources like Viz, Crunchyroll, or publisher websites\n• Use --list to verify available chapters")
 	}
 
 	output += fmt.Sprintf("\nTotal downloaded: %d pages from %d chapters\n", len(allFiles), len(downloadedChapters))
 
 	// Save to CBZ if requested
-	if saveCBZ && len(allFiles) > 0 {
-		if len(downloadedChapters) == 1 {
-			// Single chapter - use normal filename
-			chapter := downloadedChapters[0]
-			cbzFilename := packer.GetCBZFilename(title, chapter.Number, chapter.Title)
-			if outputDir != "" {
-				cbzFilename = filepath.Join(outputDir, filepath.Base(cbzFilename))
-				// Create output directory if it doesn't exist
-				if err := os.MkdirAll(outputDir, 0755); err != nil {
-					return "", fmt.Errorf("failed to create output directory: %w", err)
+	var packedChapters []*grabber.Chapter
+	if opts.SaveCBZ && len(allFiles) > 0 {
+		covers := loadCovers(site)
+		s := &series{title: title, covers: covers, metadata: loadMetadata(site)}
+		if saved, err := covers.saveSeriesCover(opts.OutputDir); err != nil {
+			colors.WarningPrintf("Warning: %v\n", err)
+		} else if saved != "" {
+			output += fmt.Sprintf("Saved cover: %s\n", saved)
+		}
+
+		if opts.ByVolume {
+			// One CBZ per volume, chapters without a volume are packed on their own
+			volumes := make(map[string][]*grabber.Chapter)
+			var volumeOrder []string
+			for _, chapter := range downloadedChapters {
+				if chapter.Volume == "" {
+					filename := packer.GetLabeledCBZFilename(title, grabber.ChapterName(chapter), chapter.Title)
+					packed, err := packChapters(filename, s, []*grabber.Chapter{chapter}, chapterFiles, opts)
+					if err != nil {
+						return "", err
+					}
+					output += packed
+					packedChapters = append(packedChapters, chapter)
+					continue
 				}
-			}
 
-			// Remove existing file if it exists
-			if _, err := os.Stat(cbzFilename); err == nil {
-				os.Remove(cbzFilename)
+				if _, exists := volumes[chapter.Volume]; !exists {
+					volumeOrder = append(volumeOrder, chapter.Volume)
+				}
+				volumes[chapter.Volume] = append(volumes[chapter.Volume], chapter)
 			}
 
-			colors.SavedPrintf("saving to cbz\n")
-
-			packingCallback := func(page, progress int) {
-				// Silent packing
+			for _, volume := range volumeOrder {
+				packed, err := packChapters(packer.GetVolumeCBZFilename(title, volume), s, volumes[volume], chapterFiles, opts)
+				if err != nil {
+					return "", err
+				}
+				output += packed
+				packedChapters = append(packedChapters, volumes[volume]...)
 			}
-
-			err := packer.ArchiveCBZ(cbzFilename, allFiles, packingCallback)
+		} else if len(downloadedChapters) == 1 {
+			// Single chapter - use normal filename
+			chapter := downloadedChapters[0]
+			packed, err := packChapters(packer.GetLabeledCBZFilename(title, grabber.ChapterName(chapter), chapter.Title), s, downloadedChapters, chapterFiles, opts)
 			if err != nil {
-				return "", fmt.Errorf("error creating CBZ file: %w", err)
-			}
-
-			output += fmt.Sprintf("Successfully created CBZ file: %s\n", cbzFilename)
-
-			// Convert to other formats if requested
-			if convertToAZW3 {
-				output += performConversion(cbzFilename, ".azw3")
-			}
-			if convertToEPUB {
-				output += performConversion(cbzFilename, ".epub")
+				return "", err
 			}
+			output += packed
+			packedChapters = downloadedChapters
 		} else {
 			// Multiple chapters - bundle them with chapter-aware naming
-			bundleFilename := packer.GetCBZFilename(title, 0, fmt.Sprintf("Chapters %s", chapterRange))
-			if outputDir != "" {
-				bundleFilename = filepath.Join(outputDir, filepath.Base(bundleFilename))
-				// Create output directory if it doesn't exist
-				if err := os.MkdirAll(outputDir, 0755); err != nil {
-					return "", fmt.Errorf("failed to create output directory: %w", err)
-				}
-			}
-
-			// Remove existing file if it exists
-			if _, err := os.Stat(bundleFilename); err == nil {
-				os.Remove(bundleFilename)
-			}
-
-			colors.SavedPrintf("saving to cbz\n")
-
-			packingCallback := func(page, progress int) {
-				// Silent packing
+			bundleName := fmt.Sprintf("Chapters %s", opts.ChapterRange)
+			if opts.ChapterRange == "" && opts.VolumeRange != "" {
+				bundleName = fmt.Sprintf("Volumes %s", opts.VolumeRange)
+			} else if opts.ChapterRange == "" && len(opts.ChapterLabels) > 0 {
+				bundleName = fmt.Sprintf("Chapters %s", strings.Join(opts.ChapterLabels, ","))
+			} else if opts.ChapterRange == "" {
+				bundleName = fmt.Sprintf("Chapters %s", chapterSpan(downloadedChapters))
 			}
-
-			err := packer.ArchiveCBZWithChapterInfo(bundleFilename, chapterFiles, packingCallback)
+			packed, err := packChapters(packer.GetCBZFilename(title, 0, bundleName), s, downloadedChapters, chapterFiles, opts)
 			if err != nil {
-				return "", fmt.Errorf("error creating bundled CBZ file: %w", err)
-			}
-
-			output += fmt.Sprintf("Successfully created bundled CBZ file: %s\n", bundleFilename)
-
-			// Convert to other formats if requested
-			if convertToAZW3 {
-				output += performConversion(bundleFilename, ".azw3")
-			}
-			if convertToEPUB {
-				output += performConversion(bundleFilename, ".epub")
+				return "", err
 			}
+			output += packed
+			packedChapters = downloadedChapters
 		}
-	} else if !saveCBZ {
+	} else if !opts.SaveCBZ {
 		// List downloaded file information
 		chapterFileCount := make(map[float64]int)
 		for _, file := range allFiles {
@@ -322,11 +556,180 @@ func fetchChapterRange(mangadx *grabber.Mangadx, chapters grabber.Filterables, c
 		output += fmt.Sprintf("Total downloaded data: %d bytes\n", chapterFileCount[0])
 	}
 
+	// only the chapters saved to a file count as read
+	if opts.MarkRead && len(packedChapters) > 0 {
+		output += markRead(marker, packedChapters)
+	}
+
 	return output, nil
 }
 
-// performConversion converts a CBZ file to the specified format
-func performConversion(cbzFile string, format string) string {
+// packChapters archives the files of the given chapters into a CBZ file and converts it to the requested formats
+func packChapters(filename string, s *series, chapters []*grabber.Chapter, chapterFiles map[string][]*downloader.File, opts Options) (string, error) {
+	output := ""
+
+	if opts.OutputDir != "" {
+		filename = filepath.Join(opts.OutputDir, filepath.Base(filename))
+		// Create output directory if it doesn't exist
+		if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Remove existing file if it exists
+	if _, err := os.Stat(filename); err == nil {
+		os.Remove(filename)
+	}
+
+	colors.SavedPrintf("saving to cbz\n")
+
+	packingCallback := func(page, progress int) {
+		// Silent packing
+	}
+
+	if len(chapters) == 1 {
+		files := chapterFiles[grabber.ChapterKey(chapters[0])]
+
+		if err := packer.ArchiveCBZ(filename, files, packingCallback); err != nil {
+			return "", fmt.Errorf("error creating CBZ file: %w", err)
+		}
+
+		output += fmt.Sprintf("Successfully created CBZ file: %s\n", filename)
+	} else {
+		// pack the chapters in reading order, labelled chapters like extras after the numbered ones
+		sorted := append([]*grabber.Chapter{}, chapters...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return grabber.ChapterLess(sorted[i], sorted[j])
+		})
+
+		files := make([]packer.ChapterFiles, 0, len(sorted))
+		for _, chapter := range sorted {
+			files = append(files, packer.ChapterFiles{
+				Label: grabber.ChapterName(chapter),
+				Files: chapterFiles[grabber.ChapterKey(chapter)],
+			})
+		}
+
+		if err := packer.ArchiveCBZChapters(filename, files, packingCallback); err != nil {
+			return "", fmt.Errorf("error creating bundled CBZ file: %w", err)
+		}
+
+		output += fmt.Sprintf("Successfully created bundled CBZ file: %s\n", filename)
+	}
+
+	info := comicInfo(s.title, chapters, chapterFiles)
+	applyMetadata(info, s.metadata)
+
+	// the cover of the packed volume, or the series cover, goes before the first page
+	var entries []packer.Entry
+	cover := s.covers.image(info.Volume)
+	if cover != nil {
+		entries = append(entries, packer.CoverEntry(cover))
+		info.PageCount++
+	}
+
+	infoEntry, err := info.Entry()
+	if err != nil {
+		return "", err
+	}
+	if err := packer.AddEntries(filename, append(entries, infoEntry)); err != nil {
+		return "", fmt.Errorf("error writing CBZ metadata: %w", err)
+	}
+	if info.Notes != "" {
+		output += info.Notes + "\n"
+	}
+
+	// Convert to other formats if requested
+	if opts.ConvertToAZW3 || opts.ConvertToEPUB {
+		coverFile := ""
+		if cover != nil {
+			if coverFile, err = writeTempCover(cover); err != nil {
+				colors.WarningPrintf("Warning: could not pass the cover to ebook-convert: %v\n", err)
+			} else {
+				defer os.Remove(coverFile)
+			}
+		}
+
+		meta := ebookMetadata(filename, info, s.metadata)
+		longStrip := isLongStrip(chapters)
+		if opts.ConvertToAZW3 {
+			output += performConversion(filename, ".azw3", coverFile, meta, longStrip)
+		}
+		if opts.ConvertToEPUB {
+			output += performConversion(filename, ".epub", coverFile, meta, longStrip)
+		}
+	}
+
+	return output, nil
+}
+
+// comicInfo builds the CBZ metadata of the given chapters
+func comicInfo(title string, chapters []*grabber.Chapter, chapterFiles map[string][]*downloader.File) *packer.ComicInfo {
+	info := &packer.ComicInfo{Series: title}
+
+	var groups, qualities []string
+	volumes := make(map[string]bool)
+	languages := make(map[string]bool)
+	for _, chapter := range chapters {
+		info.PageCount += len(chapterFiles[grabber.ChapterKey(chapter)])
+		volumes[chapter.Volume] = true
+		languages[chapter.Language] = true
+		groups = appendUnique(groups, chapter.Groups...)
+		if chapter.Quality != "" {
+			qualities = appendUnique(qualities, chapter.Quality)
+		}
+	}
+
+	if len(chapters) == 1 {
+		info.Title = chapters[0].Title
+		info.Number = grabber.ChapterName(chapters[0])
+	}
+	if len(volumes) == 1 {
+		info.Volume = chapters[0].Volume
+	}
+	if len(languages) == 1 {
+		info.LanguageISO = chapters[0].Language
+	}
+	if isLongStrip(chapters) {
+		info.Format = packer.FormatLongStrip
+	}
+	info.ScanInformation = strings.Join(groups, ", ")
+	if len(qualities) > 0 {
+		info.Notes = fmt.Sprintf("Image quality: %s", strings.Join(qualities, ", "))
+	}
+
+	return info
+}
+
+// isLongStrip reports whether the chapters are all vertical comics
+func isLongStrip(chapters []*grabber.Chapter) bool {
+	for _, chapter := range chapters {
+		if !chapter.LongStrip {
+			return false
+		}
+	}
+	return len(chapters) > 0
+}
+
+// appendUnique appends the values that are not in the slice yet
+func appendUnique(slice []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, s := range slice {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			slice = append(slice, v)
+		}
+	}
+	return slice
+}
+
+// performConversion converts a CBZ file to the specified format, using the cover image when given
+func performConversion(cbzFile string, format string, cover string, meta *converter.Metadata, longStrip bool) string {
 	output := ""
 
 	// Check if ebook-convert is available
@@ -338,6 +741,9 @@ func performConversion(cbzFile string, format string) string {
 
 	conv := converter.NewConverter()
 	conv.DeleteSource = false // Keep CBZ file by default
+	conv.Cover = cover
+	conv.Metadata = meta
+	conv.LongStrip = longStrip
 
 	// Set output directory if specified
 	if outputDir := filepath.Dir(cbzFile); outputDir != "." {
@@ -389,108 +795,306 @@ func expandPath(path string) string {
 }
 
 // listAvailableChapters formats and returns a list of all available chapters
-func listAvailableChapters(title string, chapters grabber.Filterables) (string, error) {
+func listAvailableChapters(title string, chapters grabber.Filterables, settings grabber.Settings) (string, error) {
 	if len(chapters) == 0 {
 		return fmt.Sprintf("Title: %s\nNo chapters available.\n", title), nil
 	}
 
-	// Collect and sort chapter numbers
-	var chapterNumbers []float64
-	chapterMap := make(map[float64]grabber.Filterable)
+	// Collect and sort chapters, showing the release that would be downloaded
+	selected := grabber.Dedupe(chapters, settings)
+	grabber.SortChapters(selected)
+	// blocked releases are never downloaded, so their groups aren't alternatives
+	var allowed grabber.Filterables
 	for _, ch := range chapters {
-		num := ch.GetNumber()
-		if _, exists := chapterMap[num]; !exists {
-			chapterNumbers = append(chapterNumbers, num)
-			chapterMap[num] = ch
-		}
-	}
-
-	// Sort chapters
-	for i := 0; i < len(chapterNumbers); i++ {
-		for j := i + 1; j < len(chapterNumbers); j++ {
-			if chapterNumbers[i] > chapterNumbers[j] {
-				chapterNumbers[i], chapterNumbers[j] = chapterNumbers[j], chapterNumbers[i]
-			}
+		if !settings.IsBlocked(ch) {
+			allowed = append(allowed, ch)
 		}
 	}
+	allGroups := grabber.GroupsByChapter(allowed)
 
 	// Build output
-	output := fmt.Sprintf("Title: %s\nAvailable chapters (%d total):\n\n", title, len(chapterNumbers))
+	output := fmt.Sprintf("Title: %s\nAvailable chapters (%d total):\n\n", title, len(selected))
 
-	for _, num := range chapterNumbers {
-		ch := chapterMap[num]
-		if num == float64(int64(num)) {
-			output += fmt.Sprintf("Chapter %.0f: %s (%s)\n", num, ch.GetTitle(), ch.GetLanguage())
-		} else {
-			output += fmt.Sprintf("Chapter %.1f: %s (%s)\n", num, ch.GetTitle(), ch.GetLanguage())
-		}
+	for _, ch := range selected {
+		details := chapterDetails(ch) + alternativeGroups(ch, allGroups[grabber.ChapterKey(ch)])
+		output += fmt.Sprintf("Chapter %s: %s (%s)%s\n", grabber.ChapterName(ch), ch.GetTitle(), ch.GetLanguage(), details)
 	}
 
 	return output, nil
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: mango <url> [chapter_range] [--azw3] [--epub] [--list] [--output <dir>]")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece --list")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-5")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1,3,5-10")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-3 --azw3")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-3 --epub")
-		fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-3 --azw3 --output ~/Downloads/")
-		fmt.Println("")
-		fmt.Println("Flags:")
-		fmt.Println("  --list           Show all available chapters")
-		fmt.Println("  --azw3           Download and convert to AZW3 format for Kindle")
-		fmt.Println("  --epub           Download and convert to EPUB format")
-		fmt.Println("  --output <dir>   Save files to specified directory (supports ~/)")
-		fmt.Println("")
-		fmt.Println("Notes:")
-		fmt.Println("  • Without format flags, creates CBZ file only")
-		fmt.Println("  • Requires Calibre for AZW3/EPUB conversion")
-		fmt.Println("  • Files automatically overwrite existing ones")
-		fmt.Println("  • Some chapters may be unavailable due to licensing")
-		fmt.Println("  • Use --list to see what chapters are actually available")
-		return
+// parseContentRatings parses a comma separated list of content ratings
+func parseContentRatings(value string) ([]string, error) {
+	ratings := splitList(strings.ToLower(value))
+	if len(ratings) == 0 {
+		return nil, fmt.Errorf("missing content ratings: must be one of %s or all", strings.Join(grabber.ContentRatings, ", "))
+	}
+	if len(ratings) == 1 && ratings[0] == "all" {
+		return grabber.ContentRatings, nil
 	}
 
-	url := os.Args[1]
-	var chapterRange string
-	var outputDir string
-	convertToAZW3 := false
-	convertToEPUB := false
-	listOnly := false
+	for _, rating := range ratings {
+		valid := false
+		for _, known := range grabber.ContentRatings {
+			valid = valid || rating == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid content rating '%s': must be one of %s or all", rating, strings.Join(grabber.ContentRatings, ", "))
+		}
+	}
+
+	return ratings, nil
+}
+
+// cliArgs holds the parsed command line arguments
+type cliArgs struct {
+	Options
+	positional []string
+	pick       int
+	site       string
+	since      string
+}
+
+// parseArgs parses command line arguments. The first positional argument is kept as is (URL or search query), the
+// second one is used as the chapter range.
+func parseArgs(args []string) (cliArgs, error) {
+	parsed := cliArgs{site: "mangadex"}
 
-	// Parse remaining arguments
-	for i := 2; i < len(os.Args); i++ {
-		arg := os.Args[i]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
 		if arg == "--azw3" || arg == "--awz3" {
-			convertToAZW3 = true
+			parsed.ConvertToAZW3 = true
 		} else if arg == "--epub" {
-			convertToEPUB = true
+			parsed.ConvertToEPUB = true
 		} else if arg == "--list" {
-			listOnly = true
-		} else if arg == "--output" && i+1 < len(os.Args) {
-			outputDir = expandPath(os.Args[i+1])
+			parsed.ListOnly = true
+		} else if arg == "--info" {
+			parsed.Info = true
+		} else if arg == "--by-volume" {
+			parsed.ByVolume = true
+		} else if arg == "--output" && i+1 < len(args) {
+			parsed.OutputDir = expandPath(args[i+1])
 			i++ // Skip the next argument since it's the output directory
-		} else if chapterRange == "" && !strings.HasPrefix(arg, "--") {
-			chapterRange = arg
+		} else if arg == "--labels" && i+1 < len(args) {
+			parsed.ChapterLabels = splitList(args[i+1])
+			i++
+		} else if arg == "--volumes" && i+1 < len(args) {
+			parsed.VolumeRange = args[i+1]
+			i++
+		} else if arg == "--lang" && i+1 < len(args) {
+			parsed.Settings.Languages = splitList(args[i+1])
+			if len(parsed.Settings.Languages) > 0 {
+				parsed.Settings.Language = parsed.Settings.Languages[0]
+			}
+			i++
+		} else if arg == "--mark-read" {
+			parsed.MarkRead = true
+		} else if arg == "--skip-read" {
+			parsed.SkipRead = true
+		} else if arg == "--data-saver" {
+			parsed.Settings.DataSaver = true
+		} else if arg == "--content-rating" && i+1 < len(args) {
+			ratings, err := parseContentRatings(args[i+1])
+			if err != nil {
+				return parsed, err
+			}
+			parsed.Settings.ContentRatings = ratings
+			i++
+		} else if arg == "--groups" && i+1 < len(args) {
+			parsed.Settings.PreferredGroups = splitList(args[i+1])
+			i++
+		} else if arg == "--block-groups" && i+1 < len(args) {
+			parsed.Settings.BlockedGroups = splitList(args[i+1])
+			i++
+		} else if arg == "--pick" && i+1 < len(args) {
+			pick, err := strconv.Atoi(args[i+1])
+			if err != nil || pick < 1 {
+				return parsed, fmt.Errorf("invalid --pick value '%s': must be a result number", args[i+1])
+			}
+			parsed.pick = pick
+			i++
+		} else if arg == "--since" && i+1 < len(args) {
+			parsed.since = args[i+1]
+			i++
+		} else if arg == "--site" && i+1 < len(args) {
+			parsed.site = args[i+1]
+			i++
+		} else if !strings.HasPrefix(arg, "--") {
+			parsed.positional = append(parsed.positional, arg)
 		}
 	}
 
+	if len(parsed.positional) > 1 {
+		parsed.ChapterRange = parsed.positional[1]
+	}
+
+	return parsed, nil
+}
+
+// run fetches the given URL using the parsed arguments
+func run(url string, args cliArgs) (string, error) {
+	opts := args.Options
+
 	// Auto-enable download and CBZ if conversion format is specified
-	download := convertToAZW3 || convertToEPUB
-	saveCBZ := convertToAZW3 || convertToEPUB
+	opts.Download = opts.ConvertToAZW3 || opts.ConvertToEPUB
+	opts.SaveCBZ = opts.ConvertToAZW3 || opts.ConvertToEPUB
 
 	// If no conversion format specified, just download and create CBZ
-	if !convertToAZW3 && !convertToEPUB {
-		download = true
-		saveCBZ = true
+	if !opts.ConvertToAZW3 && !opts.ConvertToEPUB {
+		opts.Download = true
+		opts.SaveCBZ = true
+	}
+
+	return FetchURLContent(url, opts)
+}
+
+func printUsage() {
+	fmt.Println("Usage: mango <url> [chapter_range] [--volumes <range>] [--by-volume] [--azw3] [--epub] [--list] [--info] [--output <dir>]")
+	fmt.Println("       mango search <query> [--site <name>] [--pick <n> [chapter_range] [flags]]")
+	fmt.Println("       mango login <username> --client-id <id> [--client-secret <secret>] [--auth-url <url>]")
+	fmt.Println("       mango logout")
+	fmt.Println("       mango follows [--since <date|days>] [--list] [flags]")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece --list")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece --info")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-5")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1,3,5-10")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-3 --azw3")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-3 --epub")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-3 --azw3 --output ~/Downloads/")
+	fmt.Println("Example: mango https://mangadx.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece --volumes 1-3 --by-volume")
+	fmt.Println("Example: mango search \"one piece\"")
+	fmt.Println("Example: mango search \"one piece\" --pick 1 1-3 --epub")
+	fmt.Println("Example: mango follows --since 14d --output ~/Manga/")
+	fmt.Println("Example: mango https://mangadex.org/chapter/5e8bc984-5f3f-4fb1-b6ee-cf7f3812b112")
+	fmt.Println("Example: mango https://mangadex.org/list/1fb1a5c5-7a94-4cb9-9d48-ee2c51a0d1c9 1-3 --output ~/Manga/")
+	fmt.Println("Example: mango ~/Downloads/one-piece/ --volumes 1-3 --by-volume --epub")
+	fmt.Println("")
+	fmt.Println("Flags:")
+	fmt.Println("  --list           Show all available chapters")
+	fmt.Println("  --info           Show the series metadata (authors, description, tags, links)")
+	fmt.Println("  --azw3           Download and convert to AZW3 format for Kindle")
+	fmt.Println("  --epub           Download and convert to EPUB format")
+	fmt.Println("  --output <dir>   Save files to specified directory (supports ~/)")
+	fmt.Println("  --volumes <r>    Select chapters by volume range (e.g. 1-3)")
+	fmt.Println("  --labels <list>  Select chapters without a number by label (e.g. Extra,Oneshot)")
+	fmt.Println("  --by-volume      Create one CBZ per volume instead of a single bundle")
+	fmt.Println("  --data-saver     Download compressed images (smaller files, lower quality)")
+	fmt.Println("  --lang <list>    Translation languages by priority (default: en, e.g. es,en)")
+	fmt.Println("  --groups <list>  Preferred scanlation groups for duplicate chapters, in order (e.g. \"Group A,Group B\")")
+	fmt.Println("  --block-groups <list>  Never download releases from these scanlation groups")
+	fmt.Println("  --content-rating <list>  Content ratings to show (default: safe,suggestive,erotica; or all)")
+	fmt.Println("  --mark-read      Mark the downloaded chapters read on your account (requires mango login)")
+	fmt.Println("  --skip-read      Leave out the chapters already read on your account (requires mango login)")
+	fmt.Println("  --site <name>    Site to search (default: mangadex)")
+	fmt.Println("  --pick <n>       Download the n-th search result")
+	fmt.Println("  --since <when>   Read the followed feed from a date (2024-01-31) or days ago (7d), default: last run")
+	fmt.Println("")
+	fmt.Println("Notes:")
+	fmt.Println("  • Without format flags, creates CBZ file only")
+	fmt.Println("  • Requires Calibre for AZW3/EPUB conversion")
+	fmt.Println("  • Files automatically overwrite existing ones")
+	fmt.Println("  • Some chapters may be unavailable due to licensing")
+	fmt.Println("  • Use --list to see what chapters are actually available")
+	fmt.Println("  • Sites without an API can be added with JSON definitions in the mango/sites config directory")
+	fmt.Println("  • Other sites can be added with executables in the mango/plugins config directory, speaking JSON over stdin/stdout")
+	fmt.Println("  • A local directory is packed again: each image folder or CBZ/ZIP archive in it is a chapter")
+	fmt.Println("  • List URLs download every title of the list into its own directory under --output")
+	fmt.Println("  • follows downloads each series into its own directory under --output")
+	fmt.Println("  • login uses a MangaDex personal API client, secrets not given by --client-secret, MANGO_CLIENT_SECRET or MANGO_PASSWORD are prompted without echo")
+}
+
+// alternativeGroups returns the annotation listing the other groups that released a chapter
+func alternativeGroups(chapter grabber.Filterable, groups []string) string {
+	var others []string
+	for _, group := range groups {
+		picked := false
+		for _, g := range chapter.GetGroups() {
+			if g == group {
+				picked = true
+			}
+		}
+		if !picked {
+			others = append(others, group)
+		}
+	}
+
+	if len(others) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (also: %s)", strings.Join(others, ", "))
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadSiteDefinitions registers the sites defined in the sites directory of the user config directory
+func loadSiteDefinitions() {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+
+	if err = grabber.LoadDefinitions(filepath.Join(dir, "mango", "sites")); err != nil {
+		colors.WarningPrintf("Warning: %v\n", err)
+	}
+}
+
+// loadPlugins registers the sites of the executables in the plugins directory of the user config directory. Every
+// plugin is run to describe itself, so it's left to the registry to call when no other site handles the URL.
+func loadPlugins() {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+
+	if err = grabber.LoadPlugins(filepath.Join(dir, "mango", "plugins")); err != nil {
+		colors.WarningPrintf("Warning: %v\n", err)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		return
+	}
+
+	var content string
+	var err error
+
+	loadSiteDefinitions()
+	grabber.RegisterLoader(loadPlugins)
+
+	if os.Args[1] == "search" {
+		content, err = runSearch(os.Args[2:])
+	} else if os.Args[1] == "follows" {
+		content, err = runFollows(os.Args[2:])
+	} else if os.Args[1] == "login" || os.Args[1] == "logout" {
+		var path string
+		path, err = auth.DefaultPath()
+		if err == nil && os.Args[1] == "login" {
+			content, err = runLogin(os.Args[2:], path, os.Stdin)
+		} else if err == nil {
+			content, err = runLogout(path)
+		}
+	} else {
+		var args cliArgs
+		args, err = parseArgs(os.Args[1:])
+		if err == nil {
+			args.Settings = withSession(args.Settings)
+			content, err = run(os.Args[1], args)
+		}
 	}
 
-	content, err := FetchURLContent(url, chapterRange, download, saveCBZ, convertToAZW3, convertToEPUB, outputDir, listOnly)
 	if err != nil {
 		colors.ErrorPrintf("Error: %v\n", err)
 		return
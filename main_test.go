@@ -1,13 +1,19 @@
 package main
 
 import (
+	"archive/zip"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.sammcclenaghan.com/mango/downloader"
 	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/packer"
 )
 
 // TestFetchURLContent_Success tests fetching content from a valid MangaDx URL.
@@ -63,7 +69,7 @@ func TestFetchURLContent_Success(t *testing.T) {
 	// Test with a real mangadex URL - this will make actual API calls
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
-	content, err := FetchURLContent(testURL, "", false, false, false, false)
+	content, err := FetchURLContent(testURL, Options{})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -83,7 +89,7 @@ func TestFetchURLContent_Success(t *testing.T) {
 func TestFetchURLContent_UnsupportedSite(t *testing.T) {
 	testURL := "https://example.com/manga"
 
-	content, err := FetchURLContent(testURL, "", false, false, false, false)
+	content, err := FetchURLContent(testURL, Options{})
 	if err == nil {
 		t.Error("Expected error for unsupported site, but got none")
 	}
@@ -102,7 +108,7 @@ func TestFetchURLContent_UnsupportedSite(t *testing.T) {
 func TestFetchURLContent_InvalidURL(t *testing.T) {
 	testURL := "not-a-valid-url"
 
-	content, err := FetchURLContent(testURL, "", false, false, false, false)
+	content, err := FetchURLContent(testURL, Options{})
 	if err == nil {
 		t.Error("Expected error for invalid URL, but got none")
 	}
@@ -116,7 +122,7 @@ func TestFetchURLContent_InvalidURL(t *testing.T) {
 func TestFetchURLContent_EmptyURL(t *testing.T) {
 	testURL := ""
 
-	content, err := FetchURLContent(testURL, "", false, false, false, false)
+	content, err := FetchURLContent(testURL, Options{})
 	if err == nil {
 		t.Error("Expected error for empty URL, but got none")
 	}
@@ -216,7 +222,7 @@ func TestFetchChapterRange(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test fetching a specific chapter
-	content, err := FetchURLContent(testURL, "1", false, false, false, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1"})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -237,7 +243,7 @@ func TestFetchChapterRange_InvalidRange(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test with invalid chapter range
-	_, err := FetchURLContent(testURL, "invalid", false, false, false)
+	_, err := FetchURLContent(testURL, Options{ChapterRange: "invalid"})
 	if err == nil {
 		t.Error("Expected error for invalid chapter number, but got none")
 	}
@@ -253,7 +259,7 @@ func TestFetchChapterRange_NonExistentRange(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test with non-existent chapter range
-	_, err := FetchURLContent(testURL, "99999", false, false, false)
+	_, err := FetchURLContent(testURL, Options{ChapterRange: "99999"})
 	if err == nil {
 		t.Error("Expected error for non-existent chapter, but got none")
 	}
@@ -269,7 +275,7 @@ func TestFetchChapterRange_WithDownload(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test fetching and downloading a specific chapter
-	content, err := FetchURLContent(testURL, "1154", true, false, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1154", Download: true})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -286,7 +292,7 @@ func TestFetchChapterRange_WithoutDownload(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test fetching without downloading
-	content, err := FetchURLContent(testURL, "1154", false, false, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1154"})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -307,7 +313,7 @@ func TestFetchChapterRange_WithCBZ(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test fetching, downloading, and saving as CBZ
-	content, err := FetchURLContent(testURL, "1154", true, true, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1154", Download: true, SaveCBZ: true})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -341,7 +347,7 @@ func TestFetchChapterRange_WithAZW3(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test with AZW3 conversion
-	content, err := FetchURLContent(testURL, "1154", true, true, true)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1154", Download: true, SaveCBZ: true, ConvertToAZW3: true})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -377,7 +383,7 @@ func TestFetchChapterRange_MultipleChapters(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test fetching multiple chapters using range syntax
-	content, err := FetchURLContent(testURL, "1-3", false, false, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1-3"})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -394,7 +400,7 @@ func TestFetchChapterRange_ComplexRange(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test with complex range syntax
-	content, err := FetchURLContent(testURL, "1,3,1152-1154", false, false, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1,3,1152-1154"})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -411,7 +417,7 @@ func TestFetchChapterRange_Deduplication(t *testing.T) {
 	testURL := "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece"
 
 	// Test with range that might have duplicates
-	content, err := FetchURLContent(testURL, "1-3", false, false, false)
+	content, err := FetchURLContent(testURL, Options{ChapterRange: "1-3"})
 	if err != nil {
 		t.Skipf("Skipping test due to API error (network/rate limit): %v", err)
 		return
@@ -428,3 +434,482 @@ func TestFetchChapterRange_Deduplication(t *testing.T) {
 		t.Log("Deduplication detected (this is expected behavior)")
 	}
 }
+
+// TestParseArgs tests command line argument parsing.
+func TestParseArgs(t *testing.T) {
+	args, err := parseArgs([]string{"https://mangadex.org/title/test", "1-3", "--epub", "--list", "--pick", "2", "--site", "MangaDex"})
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+
+	if args.positional[0] != "https://mangadex.org/title/test" {
+		t.Errorf("Expected first positional argument to be the URL, got %v", args.positional)
+	}
+
+	if args.ChapterRange != "1-3" {
+		t.Errorf("Expected chapter range '1-3', got '%s'", args.ChapterRange)
+	}
+
+	if !args.ConvertToEPUB || args.ConvertToAZW3 || !args.ListOnly {
+		t.Errorf("Unexpected flags: epub=%v azw3=%v list=%v", args.ConvertToEPUB, args.ConvertToAZW3, args.ListOnly)
+	}
+
+	if args.pick != 2 || args.site != "MangaDex" {
+		t.Errorf("Expected pick=2 site=MangaDex, got pick=%d site=%s", args.pick, args.site)
+	}
+
+	if _, err := parseArgs([]string{"query", "--pick", "zero"}); err == nil {
+		t.Error("Expected error for invalid --pick value, but got none")
+	}
+}
+
+// fakeSite is an in-memory grabber serving pages from a test server.
+type fakeSite struct {
+	pagesURL string
+}
+
+func (f *fakeSite) Test() (bool, error)         { return true, nil }
+func (f *fakeSite) FetchTitle() (string, error) { return "Fake Manga", nil }
+func (f *fakeSite) FetchChapters() (grabber.Filterables, []error) {
+	return nil, nil
+}
+func (f *fakeSite) FetchChapter(ch grabber.Filterable) (*grabber.Chapter, error) {
+	return &grabber.Chapter{
+		Number:     ch.GetNumber(),
+		Volume:     ch.GetVolume(),
+		Title:      ch.GetTitle(),
+		PagesCount: 2,
+		Pages: []grabber.Page{
+			{Number: 1, URL: f.pagesURL + "/1.jpg"},
+			{Number: 2, URL: f.pagesURL + "/2.jpg"},
+		},
+	}, nil
+}
+
+// volumeChapters returns chapters spread over two volumes plus one without volume.
+func volumeChapters() grabber.Filterables {
+	return grabber.Filterables{
+		&grabber.Chapter{Number: 1, Volume: "1", Title: "One", Language: "en"},
+		&grabber.Chapter{Number: 2, Volume: "1", Title: "Two", Language: "en"},
+		&grabber.Chapter{Number: 3, Volume: "2", Title: "Three", Language: "en"},
+		&grabber.Chapter{Number: 4, Title: "Four", Language: "en"},
+	}
+}
+
+// TestFetchChapterRange_VolumeSelection tests selecting chapters by volume.
+func TestFetchChapterRange_VolumeSelection(t *testing.T) {
+	content, err := fetchChapterRange(&fakeSite{}, volumeChapters(), "Fake Manga", Options{VolumeRange: "1"})
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Found 2 unique chapters in volumes 1") {
+		t.Errorf("Expected 2 chapters in volume 1, got:\n%s", content)
+	}
+
+	if !strings.Contains(content, "Chapter 2.0: Two (en) [Vol 1]") {
+		t.Errorf("Expected volume annotation in listing, got:\n%s", content)
+	}
+
+	if strings.Contains(content, "Three") || strings.Contains(content, "Four") {
+		t.Errorf("Expected only volume 1 chapters, got:\n%s", content)
+	}
+
+	// Chapter and volume ranges are combined
+	content, err = fetchChapterRange(&fakeSite{}, volumeChapters(), "Fake Manga", Options{ChapterRange: "2-4", VolumeRange: "1"})
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Found 1 unique chapters in range 2-4 and volumes 1") {
+		t.Errorf("Expected 1 chapter for combined selection, got:\n%s", content)
+	}
+
+	_, err = fetchChapterRange(&fakeSite{}, volumeChapters(), "Fake Manga", Options{VolumeRange: "9"})
+	if err == nil || !strings.Contains(err.Error(), "no chapters found for volumes 9") {
+		t.Errorf("Expected no chapters error for missing volume, got: %v", err)
+	}
+}
+
+// TestFetchChapterRange_ByVolume tests packing one CBZ per volume.
+func TestFetchChapterRange_ByVolume(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("image " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	outputDir := t.TempDir()
+	opts := Options{
+		ChapterRange: "1-4",
+		Download:     true,
+		SaveCBZ:      true,
+		OutputDir:    outputDir,
+		ByVolume:     true,
+	}
+
+	content, err := fetchChapterRange(&fakeSite{pagesURL: ts.URL}, volumeChapters(), "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	// pages plus the ComicInfo.xml metadata entry
+	expectedFiles := map[string]int{
+		"Fake Manga - Vol 01.cbz":           5,
+		"Fake Manga - Vol 02.cbz":           3,
+		"Fake Manga - Chapter 4 - Four.cbz": 3,
+	}
+	for name, pages := range expectedFiles {
+		reader, err := zip.OpenReader(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Errorf("Expected %s to be created: %v\n%s", name, err, content)
+			continue
+		}
+		if len(reader.File) != pages {
+			t.Errorf("Expected %d pages in %s, got %d", pages, name, len(reader.File))
+		}
+		reader.Close()
+	}
+}
+
+// coverSite is a fakeSite providing a series cover and a cover for volume 1.
+type coverSite struct {
+	fakeSite
+}
+
+func (c *coverSite) FetchCovers() ([]grabber.Cover, error) {
+	return []grabber.Cover{
+		{URL: c.pagesURL + "/covers/series.jpg"},
+		{Volume: "1", URL: c.pagesURL + "/covers/vol1.jpg"},
+	}, nil
+}
+
+// TestFetchChapterRange_Unavailable tests that external and unavailable chapters are skipped with a note.
+func TestFetchChapterRange_Unavailable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	chapters := grabber.Filterables{
+		&grabber.Chapter{Number: 1, Title: "One", Language: "en"},
+		&grabber.MangadxChapter{Chapter: grabber.Chapter{Number: 2, Title: "Two", Language: "en"}, ExternalUrl: "https://official.example.com/2"},
+		&grabber.MangadxChapter{Chapter: grabber.Chapter{Number: 3, Title: "Three", Language: "en"}, Unavailable: true},
+	}
+
+	opts := Options{ChapterRange: "1-3", Download: true, OutputDir: t.TempDir()}
+	content, err := fetchChapterRange(&fakeSite{pagesURL: ts.URL}, chapters, "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	for _, e := range []string{
+		"Chapter 2 is hosted externally, read it at https://official.example.com/2",
+		"Chapter 3 is unavailable on this site",
+		"Total downloaded: 2 pages from 1 chapters",
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, content)
+		}
+	}
+
+	opts.ChapterRange = "2"
+	_, err = fetchChapterRange(&fakeSite{pagesURL: ts.URL}, chapters, "Fake Manga", opts)
+	if err == nil || !strings.Contains(err.Error(), "https://official.example.com/2") {
+		t.Errorf("Expected error pointing to the official release, got %v", err)
+	}
+}
+
+// TestFetchChapterRange_Labels tests selecting and packing chapters without a number.
+func TestFetchChapterRange_Labels(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	chapters := grabber.Filterables{
+		&grabber.Chapter{Number: 0, Label: "0", Title: "Prologue", Language: "en"},
+		&grabber.Chapter{Label: "Oneshot", Title: "Pilot", Language: "en"},
+		&grabber.Chapter{Label: "Extra", Title: "Omake", Language: "en"},
+		&grabber.Chapter{Number: 1, Label: "1", Title: "One", Language: "en"},
+	}
+
+	outputDir := t.TempDir()
+	opts := Options{ChapterLabels: []string{"oneshot"}, Download: true, SaveCBZ: true, OutputDir: outputDir}
+	content, err := fetchChapterRange(&fakeSite{pagesURL: ts.URL}, chapters, "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Found 1 unique chapters in chapters oneshot") {
+		t.Errorf("Expected only the oneshot to be selected, got:\n%s", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "Fake Manga - Chapter Oneshot - Pilot.cbz")); err != nil {
+		t.Errorf("Expected a CBZ named after the label: %v", err)
+	}
+
+	// chapter 0 and the labelled chapters are all kept and bundled in reading order
+	opts = Options{ChapterRange: "0-1", ChapterLabels: []string{"Extra", "Oneshot"}, Download: true, SaveCBZ: true, OutputDir: outputDir}
+	content, err = fetchChapterRange(&fakeSite{pagesURL: ts.URL}, chapters, "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Found 4 unique chapters") {
+		t.Errorf("Expected 4 chapters, got:\n%s", content)
+	}
+
+	reader, err := zip.OpenReader(filepath.Join(outputDir, "Fake Manga - Chapter 0 - Chapters 0-1.cbz"))
+	if err != nil {
+		t.Fatalf("Expected bundle to be created: %v\n%s", err, content)
+	}
+	defer reader.Close()
+
+	var chapterEntries []string
+	for _, f := range reader.File {
+		if strings.HasSuffix(f.Name, "_p001.jpg") {
+			chapterEntries = append(chapterEntries, f.Name)
+		}
+	}
+	expected := "001_ch0_p001.jpg,002_ch1_p001.jpg,003_chExtra_p001.jpg,004_chOneshot_p001.jpg"
+	if strings.Join(chapterEntries, ",") != expected {
+		t.Errorf("Bundle chapters = %v, want %s", chapterEntries, expected)
+	}
+}
+
+// TestFetchChapterRange_Covers tests that covers are saved and embedded as the first CBZ entry.
+func TestFetchChapterRange_Covers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("image " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	outputDir := t.TempDir()
+	opts := Options{
+		ChapterRange: "1-4",
+		Download:     true,
+		SaveCBZ:      true,
+		OutputDir:    outputDir,
+		ByVolume:     true,
+	}
+
+	content, err := fetchChapterRange(&coverSite{fakeSite{pagesURL: ts.URL}}, volumeChapters(), "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	series, err := os.ReadFile(filepath.Join(outputDir, "cover.jpg"))
+	if err != nil || string(series) != "image /covers/series.jpg" {
+		t.Errorf("Expected the series cover to be saved, got %q (%v)\n%s", series, err, content)
+	}
+
+	// volume 1 has its own cover, the others fall back to the series cover
+	expectedCovers := map[string]string{
+		"Fake Manga - Vol 01.cbz":           "image /covers/vol1.jpg",
+		"Fake Manga - Vol 02.cbz":           "image /covers/series.jpg",
+		"Fake Manga - Chapter 4 - Four.cbz": "image /covers/series.jpg",
+	}
+	for name, want := range expectedCovers {
+		reader, err := zip.OpenReader(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Errorf("Expected %s to be created: %v", name, err)
+			continue
+		}
+
+		first := reader.File[0]
+		if first.Name != packer.CoverFilename {
+			t.Errorf("First entry of %s = %s, want %s", name, first.Name, packer.CoverFilename)
+		} else {
+			rc, _ := first.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(data) != want {
+				t.Errorf("Cover of %s = %q, want %q", name, data, want)
+			}
+		}
+		reader.Close()
+	}
+}
+
+// TestListAvailableChapters_Groups tests that --list shows the picked group and the alternatives.
+func TestListAvailableChapters_Groups(t *testing.T) {
+	chapters := grabber.Filterables{
+		&grabber.Chapter{Number: 1, Title: "One", Language: "en", Groups: []string{"Zeta Scans"}},
+		&grabber.Chapter{Number: 1, Title: "One", Language: "en", Groups: []string{"Alpha Scans"}},
+		&grabber.Chapter{Number: 1, Title: "One", Language: "en", Groups: []string{"Blocked", "Joint Scans"}},
+		&grabber.Chapter{Number: 2, Title: "Two", Language: "en", Volume: "1", Groups: []string{"Blocked"}},
+	}
+
+	settings := grabber.Settings{PreferredGroups: []string{"Zeta Scans"}, BlockedGroups: []string{"Blocked"}}
+	content, err := listAvailableChapters("Fake Manga", chapters, settings)
+	if err != nil {
+		t.Fatalf("listAvailableChapters() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Chapter 1: One (en) by Zeta Scans (also: Alpha Scans)") {
+		t.Errorf("Expected preferred group with alternatives, got:\n%s", content)
+	}
+
+	if strings.Contains(content, "Chapter 2") {
+		t.Errorf("Expected blocked release to be hidden, got:\n%s", content)
+	}
+
+	if strings.Contains(content, "Blocked") || strings.Contains(content, "Joint Scans") {
+		t.Errorf("Expected groups of blocked releases to be left out of the alternatives, got:\n%s", content)
+	}
+}
+
+// TestParseArgs_Groups tests parsing of the group preference flags.
+func TestParseArgs_Groups(t *testing.T) {
+	args, err := parseArgs([]string{"url", "--groups", "Group A, Group B", "--block-groups", "Bad"})
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+
+	if len(args.Settings.PreferredGroups) != 2 || args.Settings.PreferredGroups[1] != "Group B" {
+		t.Errorf("Expected preferred groups [Group A Group B], got %v", args.Settings.PreferredGroups)
+	}
+
+	if len(args.Settings.BlockedGroups) != 1 || args.Settings.BlockedGroups[0] != "Bad" {
+		t.Errorf("Expected blocked groups [Bad], got %v", args.Settings.BlockedGroups)
+	}
+}
+
+// TestParseArgs_Lang tests parsing of the language priority list.
+func TestParseArgs_Lang(t *testing.T) {
+	args, err := parseArgs([]string{"url", "--lang", "es, en"})
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+
+	if args.Settings.Language != "es" {
+		t.Errorf("Expected primary language 'es', got '%s'", args.Settings.Language)
+	}
+
+	if len(args.Settings.Languages) != 2 || args.Settings.Languages[1] != "en" {
+		t.Errorf("Expected languages [es en], got %v", args.Settings.Languages)
+	}
+}
+
+func TestParseArgs_Labels(t *testing.T) {
+	args, err := parseArgs([]string{"url", "--labels", "Extra, Oneshot"})
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+
+	if strings.Join(args.ChapterLabels, ",") != "Extra,Oneshot" {
+		t.Errorf("Expected labels [Extra Oneshot], got %v", args.ChapterLabels)
+	}
+}
+
+func TestParseArgs_ContentRating(t *testing.T) {
+	args, err := parseArgs([]string{"url", "--content-rating", "Safe,suggestive"})
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+
+	if strings.Join(args.Settings.ContentRatings, ",") != "safe,suggestive" {
+		t.Errorf("Expected content ratings [safe suggestive], got %v", args.Settings.ContentRatings)
+	}
+
+	args, err = parseArgs([]string{"url", "--content-rating", "all"})
+	if err != nil || len(args.Settings.ContentRatings) != len(grabber.ContentRatings) {
+		t.Errorf("Expected every content rating for 'all', got %v (%v)", args.Settings.ContentRatings, err)
+	}
+
+	if _, err := parseArgs([]string{"url", "--content-rating", "safe,spicy"}); err == nil {
+		t.Error("Expected error for unknown content rating, but got none")
+	}
+}
+
+// TestComicInfo tests the metadata written to packed chapters.
+func TestComicInfo(t *testing.T) {
+	chapters := []*grabber.Chapter{
+		{Number: 1, Volume: "1", Title: "One", Language: "en", Groups: []string{"Alpha"}, Quality: grabber.QualityDataSaver},
+		{Number: 2, Volume: "1", Title: "Two", Language: "en", Groups: []string{"Alpha", "Beta"}, Quality: grabber.QualityDataSaver},
+	}
+	files := map[string][]*downloader.File{
+		"1": {{Page: 1}, {Page: 2}},
+		"2": {{Page: 1}},
+	}
+
+	info := comicInfo("Fake Manga", chapters, files)
+
+	if info.Series != "Fake Manga" || info.Volume != "1" || info.LanguageISO != "en" {
+		t.Errorf("Unexpected series/volume/language: %+v", info)
+	}
+
+	if info.Number != "" || info.Title != "" {
+		t.Errorf("Expected no chapter number or title for a bundle, got %+v", info)
+	}
+
+	if info.PageCount != 3 {
+		t.Errorf("Expected 3 pages, got %d", info.PageCount)
+	}
+
+	if info.ScanInformation != "Alpha, Beta" {
+		t.Errorf("Expected groups 'Alpha, Beta', got '%s'", info.ScanInformation)
+	}
+
+	if info.Notes != "Image quality: data-saver" {
+		t.Errorf("Expected data-saver quality note, got '%s'", info.Notes)
+	}
+
+	single := comicInfo("Fake Manga", chapters[:1], files)
+	if single.Number != "1" || single.Title != "One" {
+		t.Errorf("Expected chapter number and title for a single chapter, got %+v", single)
+	}
+
+	if info.Format != "" {
+		t.Errorf("Expected no format for paged chapters, got '%s'", info.Format)
+	}
+
+	chapters[0].LongStrip = true
+	if info := comicInfo("Fake Manga", chapters, files); info.Format != "" {
+		t.Errorf("Expected no format when only some chapters are long strips, got '%s'", info.Format)
+	}
+
+	chapters[1].LongStrip = true
+	if info := comicInfo("Fake Manga", chapters, files); info.Format != packer.FormatLongStrip {
+		t.Errorf("Expected the long strip format, got '%s'", info.Format)
+	}
+}
+
+// TestFetchURLContent_LocalDirectory tests repacking chapters already on disk into volumes.
+func TestFetchURLContent_LocalDirectory(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "Old Manga")
+	for _, chapter := range []string{"Old Manga v01 c001", "Old Manga v01 c002", "Old Manga v02 c003"} {
+		if err := os.MkdirAll(filepath.Join(source, chapter), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, page := range []string{"1.jpg", "2.jpg"} {
+			if err := os.WriteFile(filepath.Join(source, chapter, page), []byte(chapter+" "+page), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	outputDir := t.TempDir()
+	opts := Options{VolumeRange: "1", Download: true, SaveCBZ: true, ByVolume: true, OutputDir: outputDir}
+
+	content, err := FetchURLContent("file://"+source, opts)
+	if err != nil {
+		t.Fatalf("FetchURLContent() error = %v", err)
+	}
+
+	reader, err := zip.OpenReader(filepath.Join(outputDir, "Old Manga - Vol 01.cbz"))
+	if err != nil {
+		t.Fatalf("Expected the volume to be packed: %v\n%s", err, content)
+	}
+	defer reader.Close()
+
+	// pages of chapters 1 and 2 plus the ComicInfo.xml metadata entry
+	if len(reader.File) != 5 {
+		t.Errorf("Expected 5 entries in the volume, got %d", len(reader.File))
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "Old Manga - Vol 02.cbz")); err == nil {
+		t.Error("Expected volume 2 to be left out of the selection")
+	}
+}
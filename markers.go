@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.sammcclenaghan.com/mango/colors"
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+// readMarker returns the read markers of the site when --mark-read or --skip-read was given, nil otherwise
+func readMarker(site grabber.GrabberInterface, opts Options) (grabber.ReadMarker, error) {
+	if !opts.MarkRead && !opts.SkipRead {
+		return nil, nil
+	}
+
+	marker, ok := site.(grabber.ReadMarker)
+	if !ok {
+		return nil, fmt.Errorf("this site does not support read markers, drop --mark-read and --skip-read")
+	}
+
+	return marker, nil
+}
+
+// skipRead drops the chapters the user already read, reading any release of a chapter counts
+func skipRead(marker grabber.ReadMarker, chapters grabber.Filterables) (grabber.Filterables, int, error) {
+	read, err := marker.ReadChapters(chapters)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching read markers: %w", err)
+	}
+
+	readKeys := make(map[string]bool, len(read))
+	for _, chapter := range read {
+		readKeys[grabber.ChapterKey(chapter)] = true
+	}
+
+	var unread grabber.Filterables
+	for _, chapter := range chapters {
+		if !readKeys[grabber.ChapterKey(chapter)] {
+			unread = append(unread, chapter)
+		}
+	}
+
+	return unread, len(chapters) - len(unread), nil
+}
+
+// markRead marks the packed chapters read, a failure is only a warning since the files were already saved
+func markRead(marker grabber.ReadMarker, chapters []*grabber.Chapter) string {
+	read := make(grabber.Filterables, 0, len(chapters))
+	for _, chapter := range chapters {
+		read = append(read, chapter)
+	}
+
+	if err := marker.MarkRead(read); err != nil {
+		colors.WarningPrintf("Warning: error marking chapters read: %v\n", err)
+		return ""
+	}
+
+	return fmt.Sprintf("Marked %d chapters read\n", len(chapters))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+// markerSite is a fakeSite keeping read markers by chapter title
+type markerSite struct {
+	fakeSite
+	read   map[string]bool
+	marked []string
+}
+
+func (m *markerSite) ReadChapters(chapters grabber.Filterables) (grabber.Filterables, error) {
+	var read grabber.Filterables
+	for _, chapter := range chapters {
+		if m.read[chapter.GetTitle()] {
+			read = append(read, chapter)
+		}
+	}
+	return read, nil
+}
+
+func (m *markerSite) MarkRead(chapters grabber.Filterables) error {
+	for _, chapter := range chapters {
+		m.marked = append(m.marked, grabber.ChapterName(chapter))
+	}
+	return nil
+}
+
+func TestFetchChapterRange_ReadMarkers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer ts.Close()
+
+	chapters := grabber.Filterables{
+		&grabber.Chapter{Number: 1, Label: "1", Title: "One", Language: "en", Groups: []string{"A"}},
+		&grabber.Chapter{Number: 1, Label: "1", Title: "One again", Language: "en", Groups: []string{"B"}},
+		&grabber.Chapter{Number: 2, Label: "2", Title: "Two", Language: "en"},
+		&grabber.Chapter{Number: 3, Label: "3", Title: "Three", Language: "en"},
+	}
+
+	// reading any release of chapter 1 skips it
+	site := &markerSite{fakeSite: fakeSite{pagesURL: ts.URL}, read: map[string]bool{"One again": true}}
+	opts := Options{ChapterRange: "1-3", Download: true, SaveCBZ: true, OutputDir: t.TempDir(), SkipRead: true, MarkRead: true}
+
+	content, err := fetchChapterRange(site, chapters, "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+
+	if !strings.Contains(content, "Found 2 unique chapters") || !strings.Contains(content, "Marked 2 chapters read") {
+		t.Errorf("unexpected output:\n%s", content)
+	}
+	if strings.Join(site.marked, ",") != "2,3" {
+		t.Errorf("marked chapters %v read, want 2 and 3", site.marked)
+	}
+
+	// nothing is saved without a CBZ file, so nothing is marked read
+	site.marked = nil
+	noCBZ := opts
+	noCBZ.SaveCBZ = false
+	content, err = fetchChapterRange(site, chapters, "Fake Manga", noCBZ)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+	if len(site.marked) != 0 || strings.Contains(content, "Marked") {
+		t.Errorf("marked chapters %v read without saving them:\n%s", site.marked, content)
+	}
+
+	site.read = map[string]bool{"One": true, "Two": true, "Three": true}
+	content, err = fetchChapterRange(site, chapters, "Fake Manga", opts)
+	if err != nil {
+		t.Fatalf("fetchChapterRange() error = %v", err)
+	}
+	if !strings.Contains(content, "already read") {
+		t.Errorf("expected every chapter to be skipped, got:\n%s", content)
+	}
+
+	// sites without read markers can't honour the flags
+	if _, err = fetchChapterRange(&fakeSite{}, chapters, "Fake Manga", Options{SkipRead: true}); err == nil {
+		t.Error("fetchChapterRange() error = nil for --skip-read on a site without read markers")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/colors"
+	"github.sammcclenaghan.com/mango/converter"
+	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/packer"
+)
+
+// series holds what is known about the series being packed
+type series struct {
+	title    string
+	covers   *coverArt
+	metadata *grabber.SeriesMetadata
+}
+
+// loadMetadata fetches the metadata of the series, returning nil when the site has none
+func loadMetadata(site grabber.GrabberInterface) *grabber.SeriesMetadata {
+	fetcher, ok := site.(grabber.MetadataFetcher)
+	if !ok {
+		return nil
+	}
+
+	meta, err := fetcher.FetchMetadata()
+	if err != nil {
+		colors.WarningPrintf("Warning: could not fetch series metadata: %v\n", err)
+		return nil
+	}
+
+	return meta
+}
+
+// seriesInfo returns the metadata of the series as shown by --info
+func seriesInfo(site grabber.GrabberInterface, title string) (string, error) {
+	fetcher, ok := site.(grabber.MetadataFetcher)
+	if !ok {
+		return "", fmt.Errorf("this site does not provide series metadata")
+	}
+
+	meta, err := fetcher.FetchMetadata()
+	if err != nil {
+		return "", fmt.Errorf("error fetching series metadata: %w", err)
+	}
+
+	return formatMetadata(title, meta), nil
+}
+
+// formatMetadata formats the series metadata, skipping the fields the site left empty
+func formatMetadata(title string, meta *grabber.SeriesMetadata) string {
+	output := fmt.Sprintf("Title: %s\n", title)
+
+	field := func(name, value string) {
+		if value != "" {
+			output += fmt.Sprintf("%s: %s\n", name, value)
+		}
+	}
+
+	field("Authors", strings.Join(meta.Authors, ", "))
+	field("Artists", strings.Join(meta.Artists, ", "))
+	field("Status", meta.Status)
+	if meta.Year != 0 {
+		field("Year", fmt.Sprint(meta.Year))
+	}
+	field("Demographic", meta.Demographic)
+	field("Original language", meta.OriginalLanguage)
+	field("Content rating", meta.ContentRating)
+	field("Genres", strings.Join(meta.Genres, ", "))
+	field("Tags", strings.Join(meta.Tags, ", "))
+
+	if len(meta.Links) > 0 {
+		output += "Links:\n"
+		for _, name := range linkNames(meta) {
+			output += fmt.Sprintf("  %s: %s\n", name, meta.Links[name])
+		}
+	}
+
+	if meta.Description != "" {
+		output += fmt.Sprintf("\n%s\n", strings.TrimSpace(meta.Description))
+	}
+
+	return output
+}
+
+// linkNames returns the names of the external links of the series in a stable order
+func linkNames(meta *grabber.SeriesMetadata) []string {
+	names := make([]string, 0, len(meta.Links))
+	for name := range meta.Links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyMetadata fills the ComicInfo fields provided by the series metadata
+func applyMetadata(info *packer.ComicInfo, meta *grabber.SeriesMetadata) {
+	if meta == nil {
+		return
+	}
+
+	info.Summary = strings.TrimSpace(meta.Description)
+	info.Year = meta.Year
+	info.Writer = strings.Join(meta.Authors, ", ")
+	info.Penciller = strings.Join(meta.Artists, ", ")
+	info.Genre = strings.Join(meta.Genres, ", ")
+	info.Tags = strings.Join(meta.Tags, ", ")
+	if len(meta.Links) > 0 {
+		// ComicInfo separates URLs with spaces
+		var links []string
+		for _, name := range linkNames(meta) {
+			links = append(links, meta.Links[name])
+		}
+		info.Web = strings.Join(links, " ")
+	}
+	if meta.OriginalLanguage == "ja" {
+		info.Manga = "YesAndRightToLeft"
+	}
+	info.AgeRating = ageRatings[meta.ContentRating]
+}
+
+// ageRatings maps content ratings to the ComicInfo age ratings
+var ageRatings = map[string]string{
+	"safe":         "Everyone",
+	"suggestive":   "Teen",
+	"erotica":      "Mature 17+",
+	"pornographic": "Adults Only 18+",
+}
+
+// ebookMetadata returns the metadata written to the ebooks converted from a CBZ file
+func ebookMetadata(filename string, info *packer.ComicInfo, meta *grabber.SeriesMetadata) *converter.Metadata {
+	m := &converter.Metadata{
+		Title:       strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)),
+		Series:      info.Series,
+		SeriesIndex: info.Volume,
+		Language:    info.LanguageISO,
+		Comments:    info.Summary,
+	}
+	if m.SeriesIndex == "" {
+		m.SeriesIndex = info.Number
+	}
+
+	if meta != nil {
+		m.Authors = appendUnique(appendUnique(nil, meta.Authors...), meta.Artists...)
+		m.Tags = append(append([]string{}, meta.Genres...), meta.Tags...)
+	}
+
+	return m
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/grabber"
+	"github.sammcclenaghan.com/mango/packer"
+)
+
+func testMetadata() *grabber.SeriesMetadata {
+	return &grabber.SeriesMetadata{
+		Title:            "Fake Manga",
+		Description:      "A description.\n",
+		Authors:          []string{"Writer"},
+		Artists:          []string{"Writer", "Artist"},
+		Genres:           []string{"Action"},
+		Tags:             []string{"Pirates"},
+		Status:           "ongoing",
+		Year:             1997,
+		OriginalLanguage: "ja",
+		ContentRating:    "suggestive",
+		Links: map[string]string{
+			"MyAnimeList": "https://myanimelist.net/manga/13",
+			"AniList":     "https://anilist.co/manga/30013",
+		},
+	}
+}
+
+func TestFormatMetadata(t *testing.T) {
+	output := formatMetadata("Fake Manga", testMetadata())
+
+	expected := []string{
+		"Title: Fake Manga\n",
+		"Authors: Writer\n",
+		"Artists: Writer, Artist\n",
+		"Year: 1997\n",
+		"Genres: Action\n",
+		"Links:\n  AniList: https://anilist.co/manga/30013\n  MyAnimeList: https://myanimelist.net/manga/13\n",
+		"\nA description.\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+
+	if strings.Contains(output, "Demographic") {
+		t.Errorf("Expected empty fields to be skipped, got:\n%s", output)
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	info := &packer.ComicInfo{Series: "Fake Manga", Volume: "1", LanguageISO: "en"}
+	applyMetadata(info, testMetadata())
+
+	if info.Summary != "A description." || info.Year != 1997 || info.Writer != "Writer" || info.Penciller != "Writer, Artist" {
+		t.Errorf("Unexpected ComicInfo metadata: %+v", info)
+	}
+
+	if info.Web != "https://anilist.co/manga/30013 https://myanimelist.net/manga/13" {
+		t.Errorf("Expected links separated by spaces, got '%s'", info.Web)
+	}
+
+	if info.Manga != "YesAndRightToLeft" || info.AgeRating != "Teen" {
+		t.Errorf("Expected manga reading direction and age rating, got %+v", info)
+	}
+
+	meta := ebookMetadata("out/Fake Manga - Vol 01.cbz", info, testMetadata())
+	if meta.Title != "Fake Manga - Vol 01" || meta.Series != "Fake Manga" || meta.SeriesIndex != "1" {
+		t.Errorf("Unexpected ebook metadata: %+v", meta)
+	}
+
+	if strings.Join(meta.Authors, ",") != "Writer,Artist" || strings.Join(meta.Tags, ",") != "Action,Pirates" {
+		t.Errorf("Expected unique authors and all tags, got %+v", meta)
+	}
+
+	// without metadata the ComicInfo is left untouched
+	bare := &packer.ComicInfo{Series: "Fake Manga"}
+	applyMetadata(bare, nil)
+	if bare.Summary != "" || bare.Writer != "" {
+		t.Errorf("Expected no metadata, got %+v", bare)
+	}
+}
@@ -0,0 +1,143 @@
+package packer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ComicInfoFilename is the name of the metadata entry read by comic readers
+const ComicInfoFilename = "ComicInfo.xml"
+
+// FormatLongStrip is the ComicInfo format of vertical comics, which readers show as a continuous strip
+const FormatLongStrip = "Webtoon"
+
+// ComicInfo holds the metadata stored as ComicInfo.xml inside a CBZ file. Fields follow the order of the ComicInfo
+// schema.
+type ComicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	Title           string   `xml:"Title,omitempty"`
+	Series          string   `xml:"Series,omitempty"`
+	Number          string   `xml:"Number,omitempty"`
+	Volume          string   `xml:"Volume,omitempty"`
+	Summary         string   `xml:"Summary,omitempty"`
+	Notes           string   `xml:"Notes,omitempty"`
+	Year            int      `xml:"Year,omitempty"`
+	Writer          string   `xml:"Writer,omitempty"`
+	Penciller       string   `xml:"Penciller,omitempty"`
+	Genre           string   `xml:"Genre,omitempty"`
+	Tags            string   `xml:"Tags,omitempty"`
+	Web             string   `xml:"Web,omitempty"`
+	PageCount       int      `xml:"PageCount,omitempty"`
+	LanguageISO     string   `xml:"LanguageISO,omitempty"`
+	Format          string   `xml:"Format,omitempty"`
+	Manga           string   `xml:"Manga,omitempty"`
+	ScanInformation string   `xml:"ScanInformation,omitempty"`
+	AgeRating       string   `xml:"AgeRating,omitempty"`
+}
+
+// Entry is a named file stored in a CBZ archive
+type Entry struct {
+	Name string
+	Data []byte
+}
+
+// Entry returns the ComicInfo.xml archive entry for the metadata
+func (c *ComicInfo) Entry() (Entry, error) {
+	data, err := xml.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to encode %s: %w", ComicInfoFilename, err)
+	}
+
+	return Entry{Name: ComicInfoFilename, Data: append([]byte(xml.Header), data...)}, nil
+}
+
+// AddComicInfo stores the metadata as ComicInfo.xml in an existing CBZ file
+func AddComicInfo(filename string, info *ComicInfo) error {
+	entry, err := info.Entry()
+	if err != nil {
+		return err
+	}
+
+	return AddEntries(filename, []Entry{entry})
+}
+
+// AddEntries rewrites an existing CBZ file placing the given entries before its current content. Existing entries
+// with the same name as a new one are replaced.
+func AddEntries(filename string, entries []Entry) error {
+	if len(entries) == 0 {
+		return errors.New("no entries to add")
+	}
+
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".mango-*.cbz")
+	if err != nil {
+		reader.Close()
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = rewriteCBZ(tmp, reader, entries)
+	reader.Close()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	// keep the permissions of the original file
+	if err := os.Chmod(tmp.Name(), stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", filename, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+// rewriteCBZ writes the new entries followed by the entries of the original archive
+func rewriteCBZ(out io.Writer, original *zip.ReadCloser, entries []Entry) error {
+	w := zip.NewWriter(out)
+
+	replaced := make(map[string]bool)
+	for _, entry := range entries {
+		f, err := w.Create(entry.Name)
+		if err != nil {
+			return fmt.Errorf("failed to create entry %s: %w", entry.Name, err)
+		}
+
+		if _, err = f.Write(entry.Data); err != nil {
+			return fmt.Errorf("failed to write data for %s: %w", entry.Name, err)
+		}
+		replaced[entry.Name] = true
+	}
+
+	for _, f := range original.File {
+		if replaced[f.Name] {
+			continue
+		}
+		if err := w.Copy(f); err != nil {
+			return fmt.Errorf("failed to copy entry %s: %w", f.Name, err)
+		}
+	}
+
+	return w.Close()
+}
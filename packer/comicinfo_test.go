@@ -0,0 +1,99 @@
+package packer
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/downloader"
+)
+
+func TestAddComicInfo(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.cbz")
+
+	files := []*downloader.File{
+		{Data: []byte("page 1 data"), Page: 1},
+		{Data: []byte("page 2 data"), Page: 2},
+	}
+	if err := ArchiveCBZ(filename, files, nil); err != nil {
+		t.Fatalf("ArchiveCBZ() error = %v", err)
+	}
+
+	info := &ComicInfo{
+		Series:    "One Piece",
+		Number:    "1",
+		PageCount: 2,
+		Notes:     "Image quality: data-saver",
+	}
+	if err := AddComicInfo(filename, info); err != nil {
+		t.Fatalf("AddComicInfo() error = %v", err)
+	}
+
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatalf("Failed to open CBZ file: %v", err)
+	}
+	defer reader.Close()
+
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+
+	if strings.Join(names, ",") != "ComicInfo.xml,001.jpg,002.jpg" {
+		t.Fatalf("Unexpected CBZ entries: %v", names)
+	}
+
+	rc, err := reader.File[0].Open()
+	if err != nil {
+		t.Fatalf("Failed to open ComicInfo.xml: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+
+	for _, expected := range []string{"<ComicInfo>", "<Series>One Piece</Series>", "<Number>1</Number>", "<Notes>Image quality: data-saver</Notes>", "<PageCount>2</PageCount>"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("ComicInfo.xml missing %s:\n%s", expected, data)
+		}
+	}
+
+	if strings.Contains(string(data), "<Volume>") {
+		t.Errorf("ComicInfo.xml should omit empty fields:\n%s", data)
+	}
+}
+
+func TestAddEntries_ReplacesExisting(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.cbz")
+
+	if err := ArchiveCBZ(filename, []*downloader.File{{Data: []byte("page"), Page: 1}}, nil); err != nil {
+		t.Fatalf("ArchiveCBZ() error = %v", err)
+	}
+
+	for _, notes := range []string{"first", "second"} {
+		if err := AddComicInfo(filename, &ComicInfo{Notes: notes}); err != nil {
+			t.Fatalf("AddComicInfo() error = %v", err)
+		}
+	}
+
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatalf("Failed to open CBZ file: %v", err)
+	}
+	defer reader.Close()
+
+	if len(reader.File) != 2 {
+		t.Errorf("Expected 2 entries after replacing ComicInfo.xml, got %d", len(reader.File))
+	}
+}
+
+func TestAddEntries_Errors(t *testing.T) {
+	if err := AddEntries(filepath.Join(t.TempDir(), "missing.cbz"), []Entry{{Name: "a", Data: []byte("a")}}); err == nil {
+		t.Error("AddEntries() expected error for missing file, but got none")
+	}
+
+	if err := AddEntries("whatever.cbz", nil); err == nil {
+		t.Error("AddEntries() expected error for no entries, but got none")
+	}
+}
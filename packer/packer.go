@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.sammcclenaghan.com/mango/downloader"
@@ -68,19 +70,32 @@ func ArchiveCBZ(filename string, files []*downloader.File, progress ProgressCall
 	return nil
 }
 
+// CoverFilename is the name of the cover entry, sorting before every page of the archive
+const CoverFilename = "000_cover.jpg"
+
+// CoverEntry returns the archive entry storing the cover image as the first page
+func CoverEntry(data []byte) Entry {
+	return Entry{Name: CoverFilename, Data: data}
+}
+
 // GetCBZFilename generates a standardized CBZ filename from manga title and chapter info
 func GetCBZFilename(title string, chapterNumber float64, chapterTitle string) string {
-	// Sanitize title for filename
-	sanitizedTitle := sanitizeFilename(title)
-
 	// Format chapter number
 	chapterStr := fmt.Sprintf("%.1f", chapterNumber)
 	if chapterNumber == float64(int64(chapterNumber)) {
 		chapterStr = fmt.Sprintf("%.0f", chapterNumber)
 	}
 
+	return GetLabeledCBZFilename(title, chapterStr, chapterTitle)
+}
+
+// GetLabeledCBZFilename generates a filename for a chapter identified by a label, like "12.5" or "Extra"
+func GetLabeledCBZFilename(title string, label string, chapterTitle string) string {
+	// Sanitize title for filename
+	sanitizedTitle := sanitizeFilename(title)
+
 	// Create base filename
-	filename := fmt.Sprintf("%s - Chapter %s", sanitizedTitle, chapterStr)
+	filename := fmt.Sprintf("%s - Chapter %s", sanitizedTitle, sanitizeFilename(label))
 
 	// Add chapter title if provided
 	if chapterTitle != "" {
@@ -91,6 +106,29 @@ func GetCBZFilename(title string, chapterNumber float64, chapterTitle string) st
 	return filename + ".cbz"
 }
 
+// GetVolumeCBZFilename generates a standardized CBZ filename for a whole volume, e.g. "Title - Vol 03.cbz"
+func GetVolumeCBZFilename(title string, volume string) string {
+	// Sanitize title for filename
+	sanitizedTitle := sanitizeFilename(title)
+
+	// Zero pad whole volume numbers so files sort naturally
+	volumeStr := sanitizeFilename(volume)
+	if num, err := strconv.ParseFloat(volume, 64); err == nil {
+		if num == float64(int64(num)) {
+			volumeStr = fmt.Sprintf("%02d", int64(num))
+		} else {
+			volumeStr = strconv.FormatFloat(num, 'f', -1, 64)
+		}
+	}
+
+	return fmt.Sprintf("%s - Vol %s.cbz", sanitizedTitle, volumeStr)
+}
+
+// GetSeriesDirname generates the name of the directory holding the files of a series
+func GetSeriesDirname(title string) string {
+	return sanitizeFilename(title)
+}
+
 // sanitizeFilename removes or replaces characters that are invalid in filenames
 func sanitizeFilename(filename string) string {
 	// Replace invalid characters with underscores
@@ -185,7 +223,34 @@ func BundleChapters(filename string, chapters map[string][]*downloader.File, pro
 
 // ArchiveCBZWithChapterInfo archives files with chapter-aware naming for better organization
 func ArchiveCBZWithChapterInfo(filename string, chapterFiles map[float64][]*downloader.File, progress ProgressCallback) error {
-	if len(chapterFiles) == 0 {
+	// Sort chapters by number for consistent ordering
+	var chapterNumbers []float64
+	for chapterNum := range chapterFiles {
+		chapterNumbers = append(chapterNumbers, chapterNum)
+	}
+	sort.Float64s(chapterNumbers)
+
+	chapters := make([]ChapterFiles, 0, len(chapterNumbers))
+	for _, chapterNum := range chapterNumbers {
+		chapters = append(chapters, ChapterFiles{
+			Label: strconv.FormatFloat(chapterNum, 'f', -1, 64),
+			Files: chapterFiles[chapterNum],
+		})
+	}
+
+	return ArchiveCBZChapters(filename, chapters, progress)
+}
+
+// ChapterFiles holds the downloaded pages of a chapter and the label naming them in an archive
+type ChapterFiles struct {
+	Label string
+	Files []*downloader.File
+}
+
+// ArchiveCBZChapters archives the pages of several chapters in the given order. Page names start with the position
+// of their chapter so that chapters labelled like "Extra" keep their place when readers sort pages by name.
+func ArchiveCBZChapters(filename string, chapters []ChapterFiles, progress ProgressCallback) error {
+	if len(chapters) == 0 {
 		return errors.New("no files to pack")
 	}
 
@@ -216,26 +281,11 @@ func ArchiveCBZWithChapterInfo(filename string, chapterFiles map[float64][]*down
 	defer w.Close()
 
 	fileIndex := 0
-	// Sort chapters by number for consistent ordering
-	var chapterNumbers []float64
-	for chapterNum := range chapterFiles {
-		chapterNumbers = append(chapterNumbers, chapterNum)
-	}
-
-	// Simple sort for chapter numbers
-	for i := 0; i < len(chapterNumbers); i++ {
-		for j := i + 1; j < len(chapterNumbers); j++ {
-			if chapterNumbers[i] > chapterNumbers[j] {
-				chapterNumbers[i], chapterNumbers[j] = chapterNumbers[j], chapterNumbers[i]
-			}
-		}
-	}
-
-	for _, chapterNum := range chapterNumbers {
-		files := chapterFiles[chapterNum]
-		for _, file := range files {
-			// Use chapter number and page number for unique filename
-			filename := fmt.Sprintf("ch%02.0f_p%03d.jpg", chapterNum, file.Page)
+	for i, chapter := range chapters {
+		label := strings.ReplaceAll(sanitizeFilename(chapter.Label), " ", "_")
+		for _, file := range chapter.Files {
+			// Use chapter position, label and page number for unique filename
+			filename := fmt.Sprintf("%03d_ch%s_p%03d.jpg", i+1, label, file.Page)
 
 			f, err := w.Create(filename)
 			if err != nil {
@@ -368,3 +368,99 @@ func TestArchiveCBZ_CreatesDirectory(t *testing.T) {
 		t.Error("CBZ file was not created")
 	}
 }
+
+func TestGetVolumeCBZFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		volume   string
+		expected string
+	}{
+		{
+			name:     "whole volume is zero padded",
+			title:    "One Piece",
+			volume:   "3",
+			expected: "One Piece - Vol 03.cbz",
+		},
+		{
+			name:     "large volume",
+			title:    "One Piece",
+			volume:   "105",
+			expected: "One Piece - Vol 105.cbz",
+		},
+		{
+			name:     "decimal volume",
+			title:    "Test Manga",
+			volume:   "2.5",
+			expected: "Test Manga - Vol 2.5.cbz",
+		},
+		{
+			name:     "non numeric volume with invalid characters",
+			title:    "Manga: Test",
+			volume:   "Extra/Side",
+			expected: "Manga_ Test - Vol Extra_Side.cbz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetVolumeCBZFilename(tt.title, tt.volume)
+			if result != tt.expected {
+				t.Errorf("GetVolumeCBZFilename() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetLabeledCBZFilename(t *testing.T) {
+	tests := []struct {
+		label    string
+		title    string
+		expected string
+	}{
+		{label: "12.5", title: "Half", expected: "Manga - Chapter 12.5 - Half.cbz"},
+		{label: "Extra", title: "", expected: "Manga - Chapter Extra.cbz"},
+		{label: "Oneshot", title: "Pilot", expected: "Manga - Chapter Oneshot - Pilot.cbz"},
+		{label: "Side/Story", title: "", expected: "Manga - Chapter Side_Story.cbz"},
+	}
+
+	for _, tt := range tests {
+		if result := GetLabeledCBZFilename("Manga", tt.label, tt.title); result != tt.expected {
+			t.Errorf("GetLabeledCBZFilename(%q) = %v, want %v", tt.label, result, tt.expected)
+		}
+	}
+}
+
+func TestArchiveCBZChapters(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bundle.cbz")
+
+	chapters := []ChapterFiles{
+		{Label: "10", Files: []*downloader.File{{Page: 1, Data: []byte("10-1")}}},
+		{Label: "10.5", Files: []*downloader.File{{Page: 1, Data: []byte("10.5-1")}}},
+		{Label: "Extra Story", Files: []*downloader.File{{Page: 1, Data: []byte("extra-1")}, {Page: 2, Data: []byte("extra-2")}}},
+	}
+
+	if err := ArchiveCBZChapters(filename, chapters, nil); err != nil {
+		t.Fatalf("ArchiveCBZChapters() error = %v", err)
+	}
+
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatalf("Failed to open CBZ: %v", err)
+	}
+	defer reader.Close()
+
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+
+	expected := []string{"001_ch10_p001.jpg", "002_ch10.5_p001.jpg", "003_chExtra_Story_p001.jpg", "003_chExtra_Story_p002.jpg"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("entries = %v, want %v", names, expected)
+	}
+
+	if err := ArchiveCBZChapters(filepath.Join(t.TempDir(), "empty.cbz"), nil, nil); err == nil {
+		t.Error("ArchiveCBZChapters() expected error for no chapters, but got none")
+	}
+}
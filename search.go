@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+// runSearch handles the search subcommand: it lists the results for a query and, when --pick is given, downloads
+// the chosen result using the remaining arguments
+func runSearch(argv []string) (string, error) {
+	args, err := parseArgs(argv)
+	if err != nil {
+		return "", err
+	}
+
+	if len(args.positional) == 0 {
+		return "", fmt.Errorf("missing search query. Usage: mango search <query> [--pick <n> [chapter_range] [flags]]")
+	}
+	query := args.positional[0]
+	args.Settings = withSession(args.Settings)
+
+	results, err := SearchTitles(query, args.site, args.Settings)
+	if err != nil {
+		return "", err
+	}
+
+	if args.pick == 0 {
+		return formatSearchResults(query, results), nil
+	}
+
+	if args.pick > len(results) {
+		return "", fmt.Errorf("cannot pick result %d: search returned %d results", args.pick, len(results))
+	}
+
+	return run(results[args.pick-1].URL, args)
+}
+
+// SearchTitles searches the given site for titles matching the query
+func SearchTitles(query string, siteName string, settings grabber.Settings) ([]grabber.SearchResult, error) {
+	if len(settings.LanguagePriority()) == 0 {
+		settings.Language = "en" // default to English
+	}
+	g := &grabber.Grabber{Settings: settings}
+
+	site, err := grabber.NewSite(siteName, g)
+	if err != nil {
+		return nil, err
+	}
+
+	searcher, ok := site.(grabber.Searcher)
+	if !ok {
+		return nil, fmt.Errorf("site %s does not support searching", siteName)
+	}
+
+	results, err := searcher.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("error searching titles: %w", err)
+	}
+
+	return results, nil
+}
+
+// formatSearchResults formats search results as a numbered list
+func formatSearchResults(query string, results []grabber.SearchResult) string {
+	if len(results) == 0 {
+		return fmt.Sprintf("No results found for \"%s\".\n", query)
+	}
+
+	output := fmt.Sprintf("Search results for \"%s\" (%d found):\n\n", query, len(results))
+
+	for i, r := range results {
+		var details []string
+		if r.Year != 0 {
+			details = append(details, fmt.Sprint(r.Year))
+		}
+		if r.Status != "" {
+			details = append(details, r.Status)
+		}
+
+		output += fmt.Sprintf("%2d. %s", i+1, r.Title)
+		if len(details) > 0 {
+			output += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+		}
+		output += "\n"
+
+		output += fmt.Sprintf("    ID: %s\n", r.Id)
+		if len(r.Languages) > 0 {
+			output += fmt.Sprintf("    Languages: %s\n", strings.Join(r.Languages, ", "))
+		}
+		output += fmt.Sprintf("    %s\n", r.URL)
+	}
+
+	output += "\nUse --pick <n> [chapter_range] to download a result.\n"
+
+	return output
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.sammcclenaghan.com/mango/grabber"
+)
+
+func TestFormatSearchResults(t *testing.T) {
+	results := []grabber.SearchResult{
+		{
+			Title:     "One Piece",
+			Id:        "a1c7c817-4e59-43b7-9365-09675a149a6f",
+			URL:       "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+			Year:      1997,
+			Status:    "ongoing",
+			Languages: []string{"en", "es"},
+		},
+		{
+			Title: "One Piece Party",
+			Id:    "b2d8f928-5f6a-44c8-a75b-09765b249b7f",
+			URL:   "https://mangadex.org/title/b2d8f928-5f6a-44c8-a75b-09765b249b7f",
+		},
+	}
+
+	output := formatSearchResults("one piece", results)
+
+	expected := []string{
+		`Search results for "one piece" (2 found)`,
+		" 1. One Piece (1997, ongoing)",
+		"Languages: en, es",
+		"https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+		" 2. One Piece Party\n",
+		"--pick",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("formatSearchResults() output missing %q:\n%s", e, output)
+		}
+	}
+}
+
+func TestFormatSearchResults_Empty(t *testing.T) {
+	output := formatSearchResults("nothing", nil)
+	if !strings.Contains(output, "No results found") {
+		t.Errorf("formatSearchResults() = %q, want no results message", output)
+	}
+}
+
+func TestSearchTitles_UnknownSite(t *testing.T) {
+	_, err := SearchTitles("one piece", "unknown", grabber.Settings{})
+	if err == nil {
+		t.Fatal("SearchTitles() expected error for unknown site, but got none")
+	}
+
+	if !strings.Contains(err.Error(), "unknown site") {
+		t.Errorf("SearchTitles() error = %v, want unknown site error", err)
+	}
+}
+
+func TestRunSearch_MissingQuery(t *testing.T) {
+	if _, err := runSearch([]string{"--pick", "1"}); err == nil {
+		t.Error("runSearch() expected error for missing query, but got none")
+	}
+}